@@ -0,0 +1,71 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ladzaretti/migrate/internal/schemaops"
+	"github.com/ladzaretti/migrate/types"
+)
+
+// Baseline records the given version as the current schema version
+// without executing any migrations.
+//
+// It is used to adopt the migrator on an existing database whose schema was
+// created by other means, and matches the state of the first version migrations
+// of the given source. The recorded checksum is the cumulative checksum of these
+// migrations, so following runs validate and apply the migrations after them
+// as usual.
+//
+// The version must be between 0 and the number of available migrations.
+// The schema version table is created if needed. If a schema version is
+// already recorded, nothing is written and an error wrapping
+// [ErrSchemaVersioned] is returned.
+func (m *Migrator) Baseline(from Lister, version int) error {
+	return m.BaselineContext(context.Background(), from, version)
+}
+
+func (m *Migrator) BaselineContext(ctx context.Context, from Lister, version int) error {
+	migrations, err := from.List()
+	if err != nil {
+		return errf("list migrations source: %w", err)
+	}
+
+	if version < 0 || version > len(migrations) {
+		return errf("invalid baseline version %d: must be between 0 and the number of available migrations (%d)", version, len(migrations))
+	}
+
+	_, err = m.withLock(ctx, func(lm *Migrator) (int, error) {
+		return 0, lm.baseline(ctx, migrations, version)
+	})
+
+	return err
+}
+
+func (m *Migrator) baseline(ctx context.Context, migrations []string, version int) error {
+	if err := schemaops.CreateTable(ctx, m.db, m.dialect); err != nil {
+		return errf("create schema version table: %w", err)
+	}
+
+	curr, err := schemaops.CurrentVersion(ctx, m.db, m.dialect)
+	if err != nil && !errors.Is(err, schemaops.ErrNoSchemaVersion) {
+		return errf("current schema version: %w", err)
+	}
+
+	if curr != nil {
+		return errf("baseline version %d: %w: version %d", version, ErrSchemaVersioned, curr.Version)
+	}
+
+	schema := types.SchemaVersion{
+		Version:  version,
+		Checksum: m.checksumHistory(migrations)[version],
+	}
+
+	if err := schemaops.SaveVersion(ctx, m.db, m.dialect, schema); err != nil {
+		return errf("save baseline version: %w", err)
+	}
+
+	m.logger.InfoContext(ctx, "schema baselined", "version", schema.Version, "checksum", schema.Checksum)
+
+	return nil
+}
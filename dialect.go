@@ -1,6 +1,10 @@
 package migrate
 
 import (
+	"errors"
+	"strings"
+
+	"github.com/ladzaretti/migrate/internal/sqlsplit"
 	"github.com/ladzaretti/migrate/types"
 )
 
@@ -8,7 +12,16 @@ import (
 // for an SQLite database.
 type SQLiteDialect struct{}
 
-var _ types.Dialect = SQLiteDialect{}
+var (
+	_ types.Dialect                = SQLiteDialect{}
+	_ types.LeaseLocker            = SQLiteDialect{}
+	_ types.HistoryDialect         = SQLiteDialect{}
+	_ types.StatementSplitter      = SQLiteDialect{}
+	_ types.TransientClassifier    = SQLiteDialect{}
+	_ types.RollbackClassifier     = SQLiteDialect{}
+	_ types.RepeatableDialect      = SQLiteDialect{}
+	_ types.AppliedVersionsDialect = SQLiteDialect{}
+)
 
 func (SQLiteDialect) CreateVersionTableQuery() string {
 	return `
@@ -34,11 +47,172 @@ func (SQLiteDialect) SaveVersionQuery() string {
 	`
 }
 
+func (SQLiteDialect) CreateHistoryTableQuery() string {
+	return `
+		CREATE TABLE
+			IF NOT EXISTS schema_version_history (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				version INTEGER NOT NULL,
+				checksum TEXT NOT NULL,
+				schema_checksum TEXT NOT NULL,
+				applied_at TIMESTAMP NOT NULL,
+				duration_ms INTEGER NOT NULL,
+				outcome TEXT NOT NULL
+			);
+	`
+}
+
+func (SQLiteDialect) SaveHistoryQuery() string {
+	return `
+		INSERT INTO schema_version_history (version, checksum, schema_checksum, applied_at, duration_ms, outcome)
+		VALUES ($1, $2, $3, $4, $5, $6);
+	`
+}
+
+func (SQLiteDialect) HistoryQuery() string {
+	return `
+		SELECT version, checksum, schema_checksum, applied_at, duration_ms, outcome
+		FROM schema_version_history
+		ORDER BY id;
+	`
+}
+
+func (SQLiteDialect) CreateRepeatableTableQuery() string {
+	return `
+		CREATE TABLE
+			IF NOT EXISTS schema_repeatable (
+				name TEXT PRIMARY KEY,
+				checksum TEXT NOT NULL
+			);
+	`
+}
+
+func (SQLiteDialect) RepeatableChecksumsQuery() string {
+	return `SELECT name, checksum FROM schema_repeatable;`
+}
+
+func (SQLiteDialect) SaveRepeatableQuery() string {
+	return `
+		INSERT INTO schema_repeatable (name, checksum)
+		VALUES ($1, $2)
+		ON CONFLICT(name)
+		DO UPDATE SET checksum = EXCLUDED.checksum;
+	`
+}
+
+func (SQLiteDialect) CreateAppliedVersionsTableQuery() string {
+	return `
+		CREATE TABLE
+			IF NOT EXISTS schema_applied_versions (
+				version INTEGER PRIMARY KEY,
+				checksum TEXT NOT NULL
+			);
+	`
+}
+
+func (SQLiteDialect) AppliedVersionsQuery() string {
+	return `SELECT version, checksum FROM schema_applied_versions;`
+}
+
+func (SQLiteDialect) SaveAppliedVersionQuery() string {
+	return `
+		INSERT INTO schema_applied_versions (version, checksum)
+		VALUES ($1, $2)
+		ON CONFLICT(version)
+		DO UPDATE SET checksum = EXCLUDED.checksum;
+	`
+}
+
+// CreateLockTableQuery returns a query creating the schema_lock_lease table,
+// holding the single lease row.
+//
+// SQLite has no session level locks, so the migration lock is a lease
+// that is taken over once it expires, see [types.LeaseLocker].
+func (SQLiteDialect) CreateLockTableQuery() string {
+	return `
+		CREATE TABLE
+			IF NOT EXISTS schema_lock_lease (
+				id INTEGER PRIMARY KEY CHECK (id = 0),
+				owner TEXT NOT NULL,
+				expires_at INTEGER NOT NULL
+			);
+	`
+}
+
+func (SQLiteDialect) AcquireLockQuery() string {
+	return `
+		INSERT INTO schema_lock_lease (id, owner, expires_at)
+		VALUES (0, $1, $2)
+		ON CONFLICT(id)
+		DO UPDATE SET owner = EXCLUDED.owner, expires_at = EXCLUDED.expires_at
+		WHERE schema_lock_lease.expires_at < $3;
+	`
+}
+
+func (SQLiteDialect) RenewLockQuery() string {
+	return `UPDATE schema_lock_lease SET expires_at = $1 WHERE id = 0 AND owner = $2;`
+}
+
+func (SQLiteDialect) ReleaseLockQuery() string {
+	return `DELETE FROM schema_lock_lease WHERE id = 0 AND owner = $1;`
+}
+
+// SplitStatements splits the script on semicolons outside of comments,
+// string literals, quoted identifiers and CREATE TRIGGER ... BEGIN ... END blocks.
+func (SQLiteDialect) SplitStatements(script string) ([]types.Statement, error) {
+	return sqlsplit.Split(script, sqlsplit.Options{TriggerBlocks: true}) //nolint:wrapcheck // error is returned from an internal package
+}
+
+// IsTransient reports whether the error is an SQLITE_BUSY or SQLITE_LOCKED error,
+// as reported by drivers exposing the SQLite result code using a Code() int method,
+// e.g., modernc.org/sqlite.
+func (SQLiteDialect) IsTransient(err error) bool {
+	const (
+		sqliteBusy   = 5
+		sqliteLocked = 6
+	)
+
+	var coder interface{ Code() int }
+	if !errors.As(err, &coder) {
+		return false
+	}
+
+	// the primary result code is held by the least significant byte of an extended result code.
+	switch coder.Code() & 0xff {
+	case sqliteBusy, sqliteLocked:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsRolledBack reports whether the error is an SQLITE_ERROR error reporting
+// that no transaction is active, as returned when rolling back a transaction
+// already rolled back by SQLite, e.g., after an interrupted write statement.
+func (SQLiteDialect) IsRolledBack(err error) bool {
+	const sqliteError = 1
+
+	var coder interface{ Code() int }
+	if !errors.As(err, &coder) {
+		return false
+	}
+
+	return coder.Code()&0xff == sqliteError && strings.Contains(err.Error(), "no transaction is active")
+}
+
 // PostgreSQLDialect provides the needed queries for managing schema versioning
 // for an PostgreSQL database.
 type PostgreSQLDialect struct{}
 
-var _ types.Dialect = PostgreSQLDialect{}
+var (
+	_ types.Dialect                = PostgreSQLDialect{}
+	_ types.Locker                 = PostgreSQLDialect{}
+	_ types.HistoryDialect         = PostgreSQLDialect{}
+	_ types.StatementSplitter      = PostgreSQLDialect{}
+	_ types.TransientClassifier    = PostgreSQLDialect{}
+	_ types.RepeatableDialect      = PostgreSQLDialect{}
+	_ types.AppliedVersionsDialect = PostgreSQLDialect{}
+)
 
 func (PostgreSQLDialect) CreateVersionTableQuery() string {
 	return `
@@ -63,3 +237,119 @@ func (PostgreSQLDialect) SaveVersionQuery() string {
 		DO UPDATE SET version = EXCLUDED.version, checksum = EXCLUDED.checksum;
 	`
 }
+
+func (PostgreSQLDialect) CreateHistoryTableQuery() string {
+	return `
+		CREATE TABLE
+			IF NOT EXISTS schema_version_history (
+				id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+				version INTEGER NOT NULL,
+				checksum TEXT NOT NULL,
+				schema_checksum TEXT NOT NULL,
+				applied_at TIMESTAMPTZ NOT NULL,
+				duration_ms BIGINT NOT NULL,
+				outcome TEXT NOT NULL
+			);
+	`
+}
+
+func (PostgreSQLDialect) SaveHistoryQuery() string {
+	return `
+		INSERT INTO schema_version_history (version, checksum, schema_checksum, applied_at, duration_ms, outcome)
+		VALUES ($1, $2, $3, $4, $5, $6);
+	`
+}
+
+func (PostgreSQLDialect) HistoryQuery() string {
+	return `
+		SELECT version, checksum, schema_checksum, applied_at, duration_ms, outcome
+		FROM schema_version_history
+		ORDER BY id;
+	`
+}
+
+func (PostgreSQLDialect) CreateRepeatableTableQuery() string {
+	return `
+		CREATE TABLE
+			IF NOT EXISTS schema_repeatable (
+				name TEXT PRIMARY KEY,
+				checksum TEXT NOT NULL
+			);
+	`
+}
+
+func (PostgreSQLDialect) RepeatableChecksumsQuery() string {
+	return `SELECT name, checksum FROM schema_repeatable;`
+}
+
+func (PostgreSQLDialect) SaveRepeatableQuery() string {
+	return `
+		INSERT INTO schema_repeatable (name, checksum)
+		VALUES ($1, $2)
+		ON CONFLICT (name)
+		DO UPDATE SET checksum = EXCLUDED.checksum;
+	`
+}
+
+func (PostgreSQLDialect) CreateAppliedVersionsTableQuery() string {
+	return `
+		CREATE TABLE
+			IF NOT EXISTS schema_applied_versions (
+				version BIGINT PRIMARY KEY,
+				checksum TEXT NOT NULL
+			);
+	`
+}
+
+func (PostgreSQLDialect) AppliedVersionsQuery() string {
+	return `SELECT version, checksum FROM schema_applied_versions;`
+}
+
+func (PostgreSQLDialect) SaveAppliedVersionQuery() string {
+	return `
+		INSERT INTO schema_applied_versions (version, checksum)
+		VALUES ($1, $2)
+		ON CONFLICT (version)
+		DO UPDATE SET checksum = EXCLUDED.checksum;
+	`
+}
+
+// LockQuery returns a query acquiring a session level advisory lock,
+// blocking until it is available.
+func (PostgreSQLDialect) LockQuery() string {
+	return `SELECT pg_advisory_lock(hashtext('schema_version'));`
+}
+
+func (PostgreSQLDialect) UnlockQuery() string {
+	return `SELECT pg_advisory_unlock(hashtext('schema_version'));`
+}
+
+// SplitStatements splits the script on semicolons outside of comments,
+// string literals, including escape strings, e.g., E'it\'s', quoted identifiers
+// and dollar-quoted strings, e.g., function bodies.
+func (PostgreSQLDialect) SplitStatements(script string) ([]types.Statement, error) {
+	return sqlsplit.Split(script, sqlsplit.Options{DollarQuotes: true, NestedComments: true, EscapeStrings: true}) //nolint:wrapcheck // error is returned from an internal package
+}
+
+// IsTransient reports whether the error is a lock_not_available, serialization_failure
+// or deadlock_detected error, as reported by drivers exposing the SQLSTATE code using
+// an SQLState() string method, e.g., github.com/jackc/pgx and github.com/lib/pq.
+func (PostgreSQLDialect) IsTransient(err error) bool {
+	const (
+		lockNotAvailable     = "55P03"
+		serializationFailure = "40001"
+		deadlockDetected     = "40P01"
+	)
+
+	var stater interface{ SQLState() string }
+	if !errors.As(err, &stater) {
+		return false
+	}
+
+	switch stater.SQLState() {
+	case lockNotAvailable, serializationFailure, deadlockDetected:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,75 @@
+package migrate
+
+import (
+	"strings"
+	"time"
+)
+
+// directivePrefix is the prefix of a directive comment line, e.g.,
+//
+//	-- migrate:no-transaction
+const directivePrefix = "migrate:"
+
+const (
+	directiveNoTransaction = "no-transaction"
+	directiveTimeout       = "timeout"
+)
+
+// directives holds the per-script options declared using
+// directive comments at the beginning of a migration script.
+type directives struct {
+	// noTx is set for scripts that must run outside a transaction.
+	noTx bool
+
+	// timeout overrides the migration timeout of the script, if positive.
+	timeout time.Duration
+}
+
+// parseDirectives parses the directive comments found at the beginning
+// of the given script. Parsing stops at the first line that is neither
+// blank nor an SQL line comment.
+//
+// An unknown directive results in an error.
+func parseDirectives(script string) (directives, error) {
+	var d directives
+
+	for line := range strings.Lines(script) {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		comment, ok := strings.CutPrefix(line, "--")
+		if !ok {
+			break
+		}
+
+		directive, ok := strings.CutPrefix(strings.TrimSpace(comment), directivePrefix)
+		if !ok {
+			continue // a regular comment
+		}
+
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), " ")
+		arg = strings.TrimSpace(arg)
+
+		switch name {
+		case directiveNoTransaction:
+			if arg != "" {
+				return directives{}, errf("directive %q takes no arguments", name)
+			}
+
+			d.noTx = true
+		case directiveTimeout:
+			timeout, err := time.ParseDuration(arg)
+			if err != nil || timeout <= 0 {
+				return directives{}, errf("invalid %s directive duration %q: must be a positive duration, e.g., 5m", name, arg)
+			}
+
+			d.timeout = timeout
+		default:
+			return directives{}, errf("unknown directive %q", name)
+		}
+	}
+
+	return d, nil
+}
@@ -0,0 +1,159 @@
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ladzaretti/migrate/types"
+)
+
+var (
+	// ErrChecksumMismatch is returned when the checksum recorded in the database
+	// does not match the checksum computed for the provided migrations,
+	// i.e., an already applied migration has changed.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+
+	// ErrVersionAhead is returned when the schema version recorded in the database
+	// exceeds the number of provided migrations.
+	ErrVersionAhead = errors.New("database version exceeds available migrations")
+
+	// ErrSchemaVersioned is returned by [Migrator.Baseline]
+	// when a schema version is already recorded in the database.
+	ErrSchemaVersioned = errors.New("schema version already recorded")
+
+	// ErrNoSchemaVersion is returned by [Migrator.Repair]
+	// when no schema version is recorded in the database.
+	ErrNoSchemaVersion = errors.New("no schema version recorded")
+
+	// ErrOutOfOrder is returned by [Migrator.ApplyVersioned] when an unapplied migration
+	// is older than the latest applied migration, unless enabled using [WithOutOfOrder].
+	ErrOutOfOrder = errors.New("out-of-order migration")
+
+	// ErrMissingMigration is returned by [Migrator.ApplyVersioned]
+	// when an applied migration is missing from the migrations source.
+	ErrMissingMigration = errors.New("applied migration missing from source")
+
+	// ErrDuplicateVersion is returned when two migrations of a source share the same version.
+	ErrDuplicateVersion = errors.New("duplicate migration version")
+
+	// ErrInvalidFileName is returned by the file based listers, e.g., [FSMigrations],
+	// when the name of a migration file does not start with a numeric version.
+	ErrInvalidFileName = errors.New("invalid migration file name")
+
+	// ErrOrphanDown is returned by the file based listers, e.g., [FSMigrations],
+	// when a down file has no matching migration file.
+	ErrOrphanDown = errors.New("down migration file without a matching migration")
+
+	// ErrVersionGap is returned by the file based listers, e.g., [FSMigrations],
+	// when the versions of the migration files are not consecutive, if gaps are disallowed.
+	ErrVersionGap = errors.New("gap in migration versions")
+
+	// ErrMigrationTimeout is returned when a migration does not complete
+	// within its timeout, see [WithMigrationTimeout].
+	ErrMigrationTimeout = errors.New("migration timeout exceeded")
+
+	// ErrRollbackFailed is returned when rolling back the transaction
+	// of a failed run fails. It is joined with the error that failed the run.
+	ErrRollbackFailed = errors.New("transaction rollback failed")
+)
+
+// MigrationError is returned when applying or reverting a migration fails.
+//
+// Use [errors.As] to retrieve it from the error returned by the [Migrator].
+type MigrationError struct {
+	// Index is the 1-based index of the failed migration in the execution order.
+	Index int
+
+	// Version is the version of the failed migration, if it is a migration of a [VersionedLister].
+	Version int64
+
+	// Name is the name of the failed migration, if known, e.g., its file name.
+	// Repeatable migrations are identified by name, and their Index is 0.
+	Name string
+
+	// Checksum is the checksum of the failed migration script.
+	Checksum string
+
+	// Statement is the failed migration script, or the ID of a failed Go function migration.
+	// With statement splitting enabled, it is the failed statement of the script.
+	Statement string
+
+	// Offset is the byte offset of the failed statement within the migration script.
+	// It is only set with statement splitting enabled, see [WithStatementSplitting].
+	Offset int
+
+	// Revert is set if the migration failed while being reverted.
+	Revert bool
+
+	// Err is the underlying error.
+	Err error
+
+	step     step
+	start    time.Time
+	duration time.Duration
+}
+
+// newMigrationError returns a [MigrationError] for the given step and error.
+func newMigrationError(s step, start time.Time, err error) *MigrationError {
+	e := &MigrationError{
+		Index:     s.index,
+		Version:   s.version,
+		Name:      s.name,
+		Checksum:  s.checksum,
+		Statement: s.script,
+		Revert:    s.revert,
+		Err:       err,
+		step:      s,
+		start:     start,
+		duration:  time.Since(start),
+	}
+
+	var se *statementError
+	if errors.As(err, &se) {
+		e.Statement = se.stmt.SQL
+		e.Offset = se.stmt.Offset
+	}
+
+	return e
+}
+
+func (e *MigrationError) Error() string {
+	action := "apply"
+	if e.Revert {
+		action = "revert"
+	}
+
+	if e.Index == 0 && e.Name != "" {
+		return fmt.Sprintf("%s repeatable migration %q: %v", action, e.Name, e.Err)
+	}
+
+	id := int64(e.Index)
+	if e.Version != 0 {
+		id = e.Version
+	}
+
+	if e.Name != "" {
+		return fmt.Sprintf("%s migration script %d (%s): %v", action, id, e.Name, e.Err)
+	}
+
+	return fmt.Sprintf("%s migration script %d: %v", action, id, e.Err)
+}
+
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
+
+// statementError is returned when a single statement of a split migration script fails.
+type statementError struct {
+	stmt types.Statement
+	err  error
+}
+
+func (e *statementError) Error() string {
+	return fmt.Sprintf("statement at offset %d: %v", e.stmt.Offset, e.err)
+}
+
+func (e *statementError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,89 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ladzaretti/migrate/internal/schemaops"
+	"github.com/ladzaretti/migrate/types"
+)
+
+// History returns the recorded history of the executed migrations,
+// in the order they were executed.
+//
+// The dialect must implement [types.HistoryDialect].
+// History is only recorded when enabled using [WithHistory].
+func (m *Migrator) History(ctx context.Context) ([]types.HistoryEntry, error) {
+	hd, err := m.historyDialect()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := schemaops.History(ctx, m.db, hd)
+	if err != nil {
+		return nil, errf("read history: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (m *Migrator) historyDialect() (types.HistoryDialect, error) {
+	hd, ok := m.dialect.(types.HistoryDialect)
+	if !ok {
+		return nil, errf("history is not supported by dialect %T", m.dialect)
+	}
+
+	return hd, nil
+}
+
+func (m *Migrator) createHistoryTable(ctx context.Context) error {
+	hd, err := m.historyDialect()
+	if err != nil {
+		return err
+	}
+
+	if err := schemaops.CreateHistoryTable(ctx, m.db, hd); err != nil {
+		return errf("create history table: %w", err)
+	}
+
+	return nil
+}
+
+func (m *Migrator) saveHistory(ctx context.Context, db types.CoreDB, s step, start time.Time, d time.Duration, outcome types.Outcome) error {
+	hd, err := m.historyDialect()
+	if err != nil {
+		return err
+	}
+
+	entry := types.HistoryEntry{
+		Version:        s.index,
+		Checksum:       s.checksum,
+		SchemaChecksum: s.schema.Checksum,
+		AppliedAt:      start.UTC(),
+		Duration:       d,
+		Outcome:        outcome,
+	}
+
+	if err := schemaops.SaveHistory(ctx, db, hd, entry); err != nil {
+		return errf("save history entry: %w", err)
+	}
+
+	return nil
+}
+
+// recordFailure records the failed step of the given error in the history,
+// if enabled. It is called once the failed step was rolled back, so the
+// entry is saved outside of any transaction.
+func (m *Migrator) recordFailure(ctx context.Context, err error) error {
+	var me *MigrationError
+	if !m.withHistory || !errors.As(err, &me) || !me.step.historic() {
+		return err
+	}
+
+	if err2 := m.saveHistory(context.WithoutCancel(ctx), m.db, me.step, me.start, me.duration, types.OutcomeFailed); err2 != nil {
+		return errors.Join(err, err2)
+	}
+
+	return err
+}
@@ -0,0 +1,167 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ladzaretti/migrate/types"
+)
+
+// HookEvent describes the point of a migration run a [Hook] is invoked at.
+type HookEvent struct {
+	// Index is the 1-based index of the migration in the execution order.
+	// It is 0 for the run level hooks, unless the run failed on a specific migration.
+	Index int
+
+	// Version is the version of the migration, if it is a migration of a [VersionedLister].
+	Version int64
+
+	// Name is the name of the migration, if known, e.g., its file name.
+	// Repeatable migrations are identified by name, and their Index is 0.
+	Name string
+
+	// Checksum is the checksum of the migration script.
+	// It is empty whenever Index is 0.
+	Checksum string
+
+	// Revert is set if the migration is being reverted rather than applied.
+	Revert bool
+
+	// Err is the error that failed the run, or the attempt for [WithOnRetry] hooks.
+	// It is only set for [WithOnError] and [WithOnRetry] hooks.
+	Err error
+}
+
+// Hook is a function invoked at a well-defined point of a migration run.
+//
+// The db argument is the active database handle: the transaction
+// the migration is executed in, or the database itself when executed
+// outside of a transaction. Run level hooks always receive the database.
+//
+// Returning an error aborts the run. When a transaction is active,
+// it is rolled back.
+type Hook func(ctx context.Context, db types.CoreDB, e HookEvent) error
+
+type hooks struct {
+	beforeRun  []Hook
+	beforeEach []Hook
+	afterEach  []Hook
+	afterRun   []Hook
+	onError    []Hook
+	onRetry    []Hook
+}
+
+// WithBeforeRun adds a [Hook] invoked once before any migration
+// of a run is executed.
+func WithBeforeRun(h Hook) Opt {
+	return func(m *Migrator) {
+		m.hooks.beforeRun = append(m.hooks.beforeRun, h)
+	}
+}
+
+// WithBeforeEach adds a [Hook] invoked before each migration is executed,
+// within the same transaction as the migration.
+func WithBeforeEach(h Hook) Opt {
+	return func(m *Migrator) {
+		m.hooks.beforeEach = append(m.hooks.beforeEach, h)
+	}
+}
+
+// WithAfterEach adds a [Hook] invoked after each migration is executed
+// and its schema version is saved, within the same transaction as the migration.
+func WithAfterEach(h Hook) Opt {
+	return func(m *Migrator) {
+		m.hooks.afterEach = append(m.hooks.afterEach, h)
+	}
+}
+
+// WithAfterRun adds a [Hook] invoked once after all migrations
+// of a run were executed and committed.
+//
+// An error returned by the hook fails the run, but the already
+// committed migrations are not rolled back.
+func WithAfterRun(h Hook) Opt {
+	return func(m *Migrator) {
+		m.hooks.afterRun = append(m.hooks.afterRun, h)
+	}
+}
+
+// WithOnError adds a [Hook] invoked once a run fails, after the active
+// transaction, if any, was rolled back, and the migration lock, if any, was released.
+//
+// It is also invoked for failures preceding the execution of the migrations,
+// e.g., a failure to list the migrations source, a checksum mismatch
+// or a migration lock timeout.
+//
+// The event holds the error that failed the run, along with the failed
+// migration, if any. An error returned by the hook is joined to the run error.
+func WithOnError(h Hook) Opt {
+	return func(m *Migrator) {
+		m.hooks.onError = append(m.hooks.onError, h)
+	}
+}
+
+// WithOnRetry adds a [Hook] invoked once a migration fails with a transient error
+// and is about to be retried, after the active transaction, if any, was rolled back.
+// See [WithRetry].
+//
+// The event holds the error that failed the attempt, along with the failed migration, if any.
+// An error returned by the hook aborts the run without retrying.
+func WithOnRetry(h Hook) Opt {
+	return func(m *Migrator) {
+		m.hooks.onRetry = append(m.hooks.onRetry, h)
+	}
+}
+
+// runHooks invokes the given hooks in order, stopping at the first error.
+func runHooks(ctx context.Context, db types.CoreDB, hs []Hook, e HookEvent) error {
+	for _, h := range hs {
+		if err := h(ctx, db, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// onError invokes the on-error hooks for the given run error, if any,
+// and returns the error joined with the errors returned by the hooks.
+func (m *Migrator) onError(ctx context.Context, err error) error {
+	if err == nil || len(m.hooks.onError) == 0 {
+		return err
+	}
+
+	e := errorEvent(err)
+	errs := []error{err}
+
+	for _, h := range m.hooks.onError {
+		if err := h(ctx, m.db, e); err != nil {
+			errs = append(errs, errf("on error hook: %w", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// onRetry invokes the on-retry hooks for the given error of a failed attempt.
+func (m *Migrator) onRetry(ctx context.Context, err error) error {
+	if err := runHooks(ctx, m.db, m.hooks.onRetry, errorEvent(err)); err != nil {
+		return errf("on retry hook: %w", err)
+	}
+
+	return nil
+}
+
+// errorEvent returns the hook event describing the given error,
+// along with the failed migration, if any.
+func errorEvent(err error) HookEvent {
+	e := HookEvent{Err: err}
+
+	var me *MigrationError
+	if errors.As(err, &me) {
+		e = me.step.event()
+		e.Err = err
+	}
+
+	return e
+}
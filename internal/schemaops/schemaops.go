@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/ladzaretti/migrate/types"
 )
@@ -25,9 +26,166 @@ func SaveVersion(ctx context.Context, db types.CoreDB, dialect types.Dialect, s
 	return execContext(ctx, db, dialect.SaveVersionQuery(), s.Version, s.Checksum)
 }
 
+func CreateHistoryTable(ctx context.Context, db types.CoreDB, dialect types.HistoryDialect) error {
+	return execContext(ctx, db, dialect.CreateHistoryTableQuery())
+}
+
+func SaveHistory(ctx context.Context, db types.CoreDB, dialect types.HistoryDialect, e types.HistoryEntry) error {
+	return execContext(ctx, db, dialect.SaveHistoryQuery(),
+		e.Version, e.Checksum, e.SchemaChecksum, e.AppliedAt, e.Duration.Milliseconds(), string(e.Outcome))
+}
+
+func History(ctx context.Context, db types.CoreDB, dialect types.HistoryDialect) (entries []types.HistoryEntry, retErr error) {
+	rows, err := db.QueryContext(ctx, dialect.HistoryQuery())
+	if err != nil {
+		return nil, fmt.Errorf("query context: %w", err)
+	}
+	defer func() { //nolint:wsl // false positive
+		if err := rows.Close(); err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("close rows: %w", err))
+		}
+	}()
+
+	for rows.Next() {
+		var (
+			e          types.HistoryEntry
+			durationMs int64
+			outcome    string
+		)
+
+		if err := rows.Scan(&e.Version, &e.Checksum, &e.SchemaChecksum, &e.AppliedAt, &durationMs, &outcome); err != nil {
+			return nil, fmt.Errorf("scan history entry: %w", err)
+		}
+
+		e.Duration = time.Duration(durationMs) * time.Millisecond
+		e.Outcome = types.Outcome(outcome)
+
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate history entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+func CreateRepeatableTable(ctx context.Context, db types.CoreDB, dialect types.RepeatableDialect) error {
+	return execContext(ctx, db, dialect.CreateRepeatableTableQuery())
+}
+
+func SaveRepeatable(ctx context.Context, db types.CoreDB, dialect types.RepeatableDialect, name string, checksum string) error {
+	return execContext(ctx, db, dialect.SaveRepeatableQuery(), name, checksum)
+}
+
+// RepeatableChecksums returns the checksums of the applied repeatable migrations keyed by their names.
+func RepeatableChecksums(ctx context.Context, db types.CoreDB, dialect types.RepeatableDialect) (checksums map[string]string, retErr error) {
+	rows, err := db.QueryContext(ctx, dialect.RepeatableChecksumsQuery())
+	if err != nil {
+		return nil, fmt.Errorf("query context: %w", err)
+	}
+	defer func() { //nolint:wsl // false positive
+		if err := rows.Close(); err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("close rows: %w", err))
+		}
+	}()
+
+	checksums = make(map[string]string)
+
+	for rows.Next() {
+		var name, checksum string
+		if err := rows.Scan(&name, &checksum); err != nil {
+			return nil, fmt.Errorf("scan repeatable checksum: %w", err)
+		}
+
+		checksums[name] = checksum
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate repeatable checksums: %w", err)
+	}
+
+	return checksums, nil
+}
+
+func CreateAppliedVersionsTable(ctx context.Context, db types.CoreDB, dialect types.AppliedVersionsDialect) error {
+	return execContext(ctx, db, dialect.CreateAppliedVersionsTableQuery())
+}
+
+func SaveAppliedVersion(ctx context.Context, db types.CoreDB, dialect types.AppliedVersionsDialect, version int64, checksum string) error {
+	return execContext(ctx, db, dialect.SaveAppliedVersionQuery(), version, checksum)
+}
+
+// AppliedVersions returns the checksums of the applied migrations keyed by their versions.
+func AppliedVersions(ctx context.Context, db types.CoreDB, dialect types.AppliedVersionsDialect) (checksums map[int64]string, retErr error) {
+	rows, err := db.QueryContext(ctx, dialect.AppliedVersionsQuery())
+	if err != nil {
+		return nil, fmt.Errorf("query context: %w", err)
+	}
+	defer func() { //nolint:wsl // false positive
+		if err := rows.Close(); err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("close rows: %w", err))
+		}
+	}()
+
+	checksums = make(map[int64]string)
+
+	for rows.Next() {
+		var (
+			version  int64
+			checksum string
+		)
+
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return nil, fmt.Errorf("scan applied version: %w", err)
+		}
+
+		checksums[version] = checksum
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate applied versions: %w", err)
+	}
+
+	return checksums, nil
+}
+
+func CreateLockTable(ctx context.Context, db types.CoreDB, dialect types.LeaseLocker) error {
+	return execContext(ctx, db, dialect.CreateLockTableQuery())
+}
+
+// AcquireLease reports whether the lease was acquired by the given owner.
+func AcquireLease(ctx context.Context, db types.CoreDB, dialect types.LeaseLocker, owner string, expiresAt time.Time, now time.Time) (bool, error) {
+	return execAffected(ctx, db, dialect.AcquireLockQuery(), owner, expiresAt.UnixMilli(), now.UnixMilli())
+}
+
+// RenewLease reports whether the lease held by the given owner was renewed.
+func RenewLease(ctx context.Context, db types.CoreDB, dialect types.LeaseLocker, owner string, expiresAt time.Time) (bool, error) {
+	return execAffected(ctx, db, dialect.RenewLockQuery(), expiresAt.UnixMilli(), owner)
+}
+
+func ReleaseLease(ctx context.Context, db types.CoreDB, dialect types.LeaseLocker, owner string) error {
+	return execContext(ctx, db, dialect.ReleaseLockQuery(), owner)
+}
+
+// execAffected executes the given query, and reports whether it affected any rows.
+func execAffected(ctx context.Context, db types.CoreDB, query string, args ...any) (bool, error) {
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("exec context: %w", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("rows affected: %w", err)
+	}
+
+	return n > 0, nil
+}
+
 func execContext(ctx context.Context, db types.CoreDB, query string, args ...any) error {
 	if _, err := db.ExecContext(ctx, query, args...); err != nil {
-		return fmt.Errorf("exec context: %v", err)
+		return fmt.Errorf("exec context: %w", err)
 	}
 
 	return nil
@@ -41,7 +199,7 @@ func scanVersion(row *sql.Row) (*types.SchemaVersion, error) {
 			return nil, ErrNoSchemaVersion
 		}
 
-		return &types.SchemaVersion{}, fmt.Errorf("scan schema version: %v", err)
+		return &types.SchemaVersion{}, fmt.Errorf("scan schema version: %w", err)
 	}
 
 	return &ver, nil
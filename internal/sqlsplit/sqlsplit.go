@@ -0,0 +1,307 @@
+// Package sqlsplit splits SQL scripts into individual statements.
+package sqlsplit
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ladzaretti/migrate/types"
+)
+
+// Options configures the dialect specific syntax recognized by [Split].
+type Options struct {
+	// DollarQuotes enables PostgreSQL dollar-quoted strings, e.g., $$ ... $$ or $body$ ... $body$.
+	DollarQuotes bool
+
+	// NestedComments enables nested block comments, as supported by PostgreSQL.
+	NestedComments bool
+
+	// TriggerBlocks enables SQLite CREATE TRIGGER ... BEGIN ... END blocks,
+	// whose body statements do not terminate the enclosing statement.
+	TriggerBlocks bool
+
+	// EscapeStrings enables PostgreSQL escape string constants, e.g., E'it\'s',
+	// within which a backslash escapes the following character.
+	EscapeStrings bool
+
+	// BackslashEscapes enables backslash escapes within all string literals,
+	// both single and double quoted, as supported by MySQL, e.g., 'it\'s'.
+	BackslashEscapes bool
+}
+
+// Split splits the given script into statements terminated by semicolons.
+//
+// Semicolons within comments, string literals, quoted identifiers and
+// the dialect specific constructs enabled by opts do not terminate a statement.
+// The terminating semicolons are not included in the returned statements,
+// and statements consisting only of comments are omitted.
+//
+// An unterminated string literal, quoted identifier or comment results in an error.
+func Split(script string, opts Options) ([]types.Statement, error) {
+	s := splitter{script: script, opts: opts, start: -1}
+
+	if err := s.split(); err != nil {
+		return nil, err
+	}
+
+	return s.statements, nil
+}
+
+type splitter struct {
+	script string
+	opts   Options
+
+	// pos is the offset of the next byte to scan.
+	pos int
+
+	// start is the offset of the first token of the current statement, or -1 if none was found yet.
+	start int
+
+	// words holds the leading words of the current statement,
+	// used to detect trigger definitions.
+	words []string
+
+	// depth is the nesting depth of BEGIN/CASE ... END blocks
+	// within the current trigger definition.
+	depth int
+
+	statements []types.Statement
+}
+
+func (s *splitter) split() error {
+	for s.pos < len(s.script) {
+		c := s.script[s.pos]
+
+		switch {
+		case c == '-' && s.peek(1) == '-':
+			s.skipLineComment()
+		case c == '/' && s.peek(1) == '*':
+			if err := s.skipBlockComment(); err != nil {
+				return err
+			}
+		case c == ';':
+			if s.depth > 0 {
+				s.pos++
+				continue
+			}
+
+			s.flush(s.pos)
+			s.pos++
+		case isSpace(c):
+			s.pos++
+		default:
+			if s.start < 0 {
+				s.start = s.pos
+			}
+
+			if err := s.token(c); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.flush(len(s.script))
+
+	return nil
+}
+
+// token scans a single token of the current statement starting with c.
+func (s *splitter) token(c byte) error {
+	switch {
+	case c == '\'':
+		return s.skipQuoted('\'', "string literal", s.opts.BackslashEscapes)
+	case c == '"':
+		return s.skipQuoted('"', "quoted identifier", s.opts.BackslashEscapes)
+	case c == '`':
+		return s.skipQuoted('`', "quoted identifier", false)
+	case c == '$' && s.opts.DollarQuotes:
+		return s.skipDollarQuoted()
+	case (c == 'E' || c == 'e') && s.peek(1) == '\'' && s.opts.EscapeStrings:
+		s.pos++
+		return s.skipQuoted('\'', "string literal", true)
+	case isWordStart(c):
+		s.word()
+	default:
+		s.pos++
+	}
+
+	return nil
+}
+
+// flush appends the current statement ending at the given offset, if any.
+func (s *splitter) flush(end int) {
+	if s.start >= 0 {
+		s.statements = append(s.statements, types.Statement{
+			SQL:    strings.TrimRight(s.script[s.start:end], " \t\n\r\f\v"),
+			Offset: s.start,
+		})
+	}
+
+	s.start = -1
+	s.words = s.words[:0]
+	s.depth = 0
+}
+
+func (s *splitter) peek(n int) byte {
+	if s.pos+n < len(s.script) {
+		return s.script[s.pos+n]
+	}
+
+	return 0
+}
+
+func (s *splitter) skipLineComment() {
+	if i := strings.IndexByte(s.script[s.pos:], '\n'); i >= 0 {
+		s.pos += i + 1
+		return
+	}
+
+	s.pos = len(s.script)
+}
+
+func (s *splitter) skipBlockComment() error {
+	start := s.pos
+	depth := 0
+
+	for s.pos < len(s.script) {
+		switch {
+		case s.script[s.pos] == '/' && s.peek(1) == '*':
+			if depth > 0 && !s.opts.NestedComments {
+				s.pos++
+				continue
+			}
+
+			depth++
+			s.pos += 2
+		case s.script[s.pos] == '*' && s.peek(1) == '/':
+			depth--
+			s.pos += 2
+
+			if depth == 0 {
+				return nil
+			}
+		default:
+			s.pos++
+		}
+	}
+
+	return fmt.Errorf("unterminated block comment at offset %d", start)
+}
+
+// skipQuoted skips a literal enclosed by the given quote character.
+// A doubled quote character within the literal is an escaped quote,
+// as is a quote character preceded by a backslash, if enabled.
+func (s *splitter) skipQuoted(quote byte, what string, backslash bool) error {
+	start := s.pos
+	s.pos++
+
+	for s.pos < len(s.script) {
+		if backslash && s.script[s.pos] == '\\' {
+			s.pos += 2
+			continue
+		}
+
+		if s.script[s.pos] != quote {
+			s.pos++
+			continue
+		}
+
+		if s.peek(1) == quote {
+			s.pos += 2
+			continue
+		}
+
+		s.pos++
+
+		return nil
+	}
+
+	return fmt.Errorf("unterminated %s at offset %d", what, start)
+}
+
+// skipDollarQuoted skips a dollar-quoted string, e.g., $tag$ ... $tag$.
+// A dollar sign not starting a dollar quote, e.g., a $1 parameter, is skipped as is.
+func (s *splitter) skipDollarQuoted() error {
+	start := s.pos
+
+	end := s.pos + 1
+	for end < len(s.script) && isWordPart(s.script[end]) {
+		end++
+	}
+
+	if end >= len(s.script) || s.script[end] != '$' || (end > s.pos+1 && !isWordStart(s.script[s.pos+1])) {
+		s.pos++
+		return nil
+	}
+
+	tag := s.script[s.pos : end+1]
+
+	i := strings.Index(s.script[end+1:], tag)
+	if i < 0 {
+		return fmt.Errorf("unterminated dollar-quoted string %s at offset %d", tag, start)
+	}
+
+	s.pos = end + 1 + i + len(tag)
+
+	return nil
+}
+
+// word scans a keyword or an unquoted identifier, keeping track
+// of the blocks of trigger definitions.
+func (s *splitter) word() {
+	start := s.pos
+
+	// dollar signs are allowed within PostgreSQL identifiers.
+	for s.pos < len(s.script) && (isWordPart(s.script[s.pos]) || (s.opts.DollarQuotes && s.script[s.pos] == '$')) {
+		s.pos++
+	}
+
+	if !s.opts.TriggerBlocks {
+		return
+	}
+
+	w := strings.ToUpper(s.script[start:s.pos])
+
+	if len(s.words) < 4 {
+		s.words = append(s.words, w)
+	}
+
+	if !s.isTrigger() {
+		return
+	}
+
+	switch w {
+	case "BEGIN", "CASE":
+		s.depth++
+	case "END":
+		if s.depth > 0 {
+			s.depth--
+		}
+	}
+}
+
+// isTrigger reports whether the current statement is a trigger definition,
+// i.e., CREATE [TEMP | TEMPORARY] TRIGGER.
+func (s *splitter) isTrigger() bool {
+	if len(s.words) < 2 || s.words[0] != "CREATE" {
+		return false
+	}
+
+	if s.words[1] == "TRIGGER" {
+		return true
+	}
+
+	return len(s.words) > 2 && (s.words[1] == "TEMP" || s.words[1] == "TEMPORARY") && s.words[2] == "TRIGGER"
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isWordStart(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c >= 0x80
+}
+
+func isWordPart(c byte) bool {
+	return isWordStart(c) || ('0' <= c && c <= '9')
+}
@@ -0,0 +1,153 @@
+package sqlsplit_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ladzaretti/migrate/internal/sqlsplit"
+	"github.com/ladzaretti/migrate/types"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		opts   sqlsplit.Options
+		want   []string
+	}{
+		{
+			name:   "empty",
+			script: "  \n-- only a comment;\n/* and another; */\n",
+			want:   nil,
+		},
+		{
+			name:   "statements",
+			script: "SELECT 1;\n  SELECT 2 ;SELECT 3",
+			want: []string{
+				"SELECT 1",
+				"SELECT 2",
+				"SELECT 3",
+			},
+		},
+		{
+			name:   "comments",
+			script: "-- a;\nSELECT 1 /* b; */ -- c;\n;",
+			want: []string{
+				"SELECT 1 /* b; */ -- c;",
+			},
+		},
+		{
+			name:   "quoted",
+			script: `SELECT 'a;''b', "c;""d", ` + "`e;f`" + `; SELECT 2`,
+			want: []string{
+				`SELECT 'a;''b', "c;""d", ` + "`e;f`",
+				"SELECT 2",
+			},
+		},
+		{
+			name:   "dollar quotes",
+			script: "CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $$ $body$ LANGUAGE sql; SELECT $1, a$b$c, $$;$$",
+			opts:   sqlsplit.Options{DollarQuotes: true},
+			want: []string{
+				"CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $$ $body$ LANGUAGE sql",
+				"SELECT $1, a$b$c, $$;$$",
+			},
+		},
+		{
+			name:   "dollar quotes disabled",
+			script: "SELECT $$;$$",
+			want: []string{
+				"SELECT $$",
+				"$$",
+			},
+		},
+		{
+			name:   "escape strings",
+			script: `INSERT INTO t VALUES (E'it\'s; here', e'\\', 'C:\'); SELECT 1;`,
+			opts:   sqlsplit.Options{EscapeStrings: true},
+			want: []string{
+				`INSERT INTO t VALUES (E'it\'s; here', e'\\', 'C:\')`,
+				"SELECT 1",
+			},
+		},
+		{
+			name:   "backslash escapes",
+			script: `INSERT INTO t VALUES ('it\'s; here', "say \"hi\";", '\\'); SELECT 1;`,
+			opts:   sqlsplit.Options{BackslashEscapes: true},
+			want: []string{
+				`INSERT INTO t VALUES ('it\'s; here', "say \"hi\";", '\\')`,
+				"SELECT 1",
+			},
+		},
+		{
+			name:   "backslash escapes disabled",
+			script: `SELECT 'C:\'; SELECT 1;`,
+			want: []string{
+				`SELECT 'C:\'`,
+				"SELECT 1",
+			},
+		},
+		{
+			name:   "nested comments",
+			script: "SELECT /* a /* b; */ c; */ 1; SELECT 2",
+			opts:   sqlsplit.Options{NestedComments: true},
+			want: []string{
+				"SELECT /* a /* b; */ c; */ 1",
+				"SELECT 2",
+			},
+		},
+		{
+			name: "trigger blocks",
+			script: `CREATE TEMP TRIGGER t AFTER INSERT ON a BEGIN
+	UPDATE a SET b = CASE WHEN new.b IS NULL THEN 0 ELSE new.b END;
+	INSERT INTO c VALUES (1);
+END; CREATE TABLE begin_end (id INTEGER)`,
+			opts: sqlsplit.Options{TriggerBlocks: true},
+			want: []string{
+				`CREATE TEMP TRIGGER t AFTER INSERT ON a BEGIN
+	UPDATE a SET b = CASE WHEN new.b IS NULL THEN 0 ELSE new.b END;
+	INSERT INTO c VALUES (1);
+END`,
+				"CREATE TABLE begin_end (id INTEGER)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sqlsplit.Split(tt.script, tt.opts)
+			if err != nil {
+				t.Fatalf("Split() returned an error: %v", err)
+			}
+
+			want := make([]types.Statement, 0, len(tt.want))
+			for _, sql := range tt.want {
+				want = append(want, types.Statement{SQL: sql, Offset: strings.LastIndex(tt.script, sql)})
+			}
+
+			if !slices.Equal(got, want) {
+				t.Errorf("Split() mismatch:\ngot  %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestSplitUnterminated(t *testing.T) {
+	scripts := []string{
+		"SELECT 'a;",
+		`SELECT "a;`,
+		"SELECT 1 /* a;",
+		"SELECT /* a /* b */ 1;",
+	}
+
+	for _, script := range scripts {
+		if _, err := sqlsplit.Split(script, sqlsplit.Options{NestedComments: true}); err == nil {
+			t.Errorf("Split(%q) expected an error", script)
+		}
+	}
+
+	if _, err := sqlsplit.Split("SELECT $a$ 1;", sqlsplit.Options{DollarQuotes: true}); err == nil {
+		t.Error("Split() expected an error for an unterminated dollar-quoted string")
+	}
+}
@@ -0,0 +1,235 @@
+package migrate
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/ladzaretti/migrate/internal/schemaops"
+	"github.com/ladzaretti/migrate/types"
+)
+
+const (
+	// lockRetryInterval is the interval between attempts to acquire a held migration lease.
+	lockRetryInterval = 100 * time.Millisecond
+
+	// leaseDuration is the duration a migration lease is valid for unless renewed,
+	// see [types.LeaseLocker].
+	leaseDuration = 30 * time.Second
+
+	// leaseRenewInterval is the interval between renewals of a held migration lease.
+	leaseRenewInterval = leaseDuration / 3
+)
+
+// errLeaseLost is returned when a held migration lease was taken over by another run.
+var errLeaseLost = errors.New("migration lock lease lost")
+
+// connector is implemented by databases that can provide
+// a dedicated connection, e.g., [*sql.DB].
+type connector interface {
+	Conn(ctx context.Context) (*sql.Conn, error)
+}
+
+// lease is a migration lease held by a run, see [types.LeaseLocker].
+type lease struct {
+	locker types.LeaseLocker
+	owner  string
+}
+
+// withLock invokes fn while holding the migration lock, if locking is enabled.
+//
+// fn is called with a copy of the migrator bound to the connection holding the lock,
+// so that the whole run is performed within the same database session.
+func (m *Migrator) withLock(ctx context.Context, fn func(m *Migrator) (int, error)) (n int, retErr error) {
+	if !m.withLocking {
+		return fn(m)
+	}
+
+	locker, isLocker := m.dialect.(types.Locker)
+	leaser, isLeaser := m.dialect.(types.LeaseLocker)
+
+	if !isLocker && !isLeaser {
+		return 0, errf("locking is not supported by dialect %T", m.dialect)
+	}
+
+	lockCtx, cancel := m.lockContext(ctx)
+	defer cancel()
+
+	lm := *m
+
+	if c, ok := m.db.(connector); ok {
+		conn, err := c.Conn(lockCtx)
+		if err != nil {
+			return 0, errf("acquire migration lock: get connection: %w", err)
+		}
+		defer func() { //nolint:wsl // false positive
+			_ = conn.Close()
+		}()
+
+		lm.db = conn
+	}
+
+	var release func(ctx context.Context) error
+
+	if isLocker {
+		if err := execContext(lockCtx, lm.db, locker.LockQuery()); err != nil {
+			m.logger.ErrorContext(ctx, "migration lock not acquired", "error", err)
+			return 0, errf("acquire migration lock: %w", err)
+		}
+
+		release = func(ctx context.Context) error {
+			return execContext(ctx, lm.db, locker.UnlockQuery())
+		}
+	} else {
+		l := &lease{locker: leaser, owner: rand.Text()}
+
+		if err := m.acquireLease(lockCtx, lm.db, l); err != nil {
+			m.logger.ErrorContext(ctx, "migration lock not acquired", "error", err)
+			return 0, err
+		}
+
+		lm.lease = l
+		stop := m.keepLease(ctx, l)
+
+		release = func(ctx context.Context) error {
+			stop()
+			return schemaops.ReleaseLease(ctx, lm.db, leaser, l.owner) //nolint:wrapcheck // error is returned from an internal package
+		}
+	}
+
+	m.logger.InfoContext(ctx, "migration lock acquired")
+
+	defer func() {
+		// release the lock even if the run was canceled.
+		if err := release(context.WithoutCancel(ctx)); err != nil {
+			m.logger.ErrorContext(ctx, "migration lock not released", "error", err)
+			retErr = errors.Join(retErr, errf("release migration lock: %w", err))
+
+			return
+		}
+
+		m.logger.InfoContext(ctx, "migration lock released")
+	}()
+
+	return fn(&lm)
+}
+
+func (m *Migrator) lockContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if m.lockTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, m.lockTimeout)
+}
+
+// acquireLease attempts to acquire the given lease until it succeeds or the context is done.
+//
+// Attempts are only retried while the lease is held by another run, or while the database
+// is busy, as classified by a dialect implementing [types.TransientClassifier].
+// Any other error is returned immediately.
+func (m *Migrator) acquireLease(ctx context.Context, db types.CoreDB, l *lease) error {
+	for {
+		acquired, err := m.tryAcquireLease(ctx, db, l)
+
+		switch {
+		case err != nil && !m.isBusy(err):
+			return errf("acquire migration lock: %w", err)
+		case acquired:
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return errf("acquire migration lock: %w", errors.Join(ctx.Err(), err))
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+func (m *Migrator) tryAcquireLease(ctx context.Context, db types.CoreDB, l *lease) (bool, error) {
+	if err := schemaops.CreateLockTable(ctx, db, l.locker); err != nil {
+		return false, errf("create lock table: %w", err)
+	}
+
+	now := time.Now()
+
+	acquired, err := schemaops.AcquireLease(ctx, db, l.locker, l.owner, now.Add(leaseDuration), now)
+	if err != nil {
+		return false, errf("acquire lease: %w", err)
+	}
+
+	return acquired, nil
+}
+
+// isBusy reports whether the given error is caused by the database
+// being locked by another session.
+func (m *Migrator) isBusy(err error) bool {
+	c, ok := m.dialect.(types.TransientClassifier)
+	return ok && c.IsTransient(err)
+}
+
+// keepLease renews the given lease periodically until the returned function is called.
+//
+// The lease is renewed using the database of the migrator rather than the connection
+// running the migrations, as the connection may be busy for longer than the lease duration.
+// The lease is also renewed along with each applied migration, see [Migrator.renewLease].
+func (m *Migrator) keepLease(ctx context.Context, l *lease) (stop func()) {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		interval := leaseRenewInterval
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+
+			interval = leaseRenewInterval
+
+			renewed, err := schemaops.RenewLease(ctx, m.db, l.locker, l.owner, time.Now().Add(leaseDuration))
+
+			switch {
+			case ctx.Err() != nil:
+				return
+			case err != nil:
+				// the database may be busy with the run itself, retry shortly.
+				m.logger.DebugContext(ctx, "migration lock not renewed", "error", err)
+				interval = lockRetryInterval
+			case !renewed:
+				m.logger.ErrorContext(ctx, "migration lock not renewed", "error", errLeaseLost)
+				return
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// renewLease renews the lease held by the run, if any, using the given database handle,
+// so that the lease is extended within the transaction applying a migration.
+func (m *Migrator) renewLease(ctx context.Context, db types.CoreDB) error {
+	if m.lease == nil {
+		return nil
+	}
+
+	renewed, err := schemaops.RenewLease(ctx, db, m.lease.locker, m.lease.owner, time.Now().Add(leaseDuration))
+	if err != nil {
+		return errf("renew migration lock: %w", err)
+	}
+
+	if !renewed {
+		return errf("renew migration lock: %w", errLeaseLost)
+	}
+
+	return nil
+}
@@ -30,11 +30,12 @@ import (
 	"context"
 	//nolint:gosec // in this context, SHA-1 is for change detection, not security.
 	"crypto/sha1"
-	"database/sql"
 	"encoding/hex"
 	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/ladzaretti/migrate/internal/schemaops"
@@ -56,7 +57,21 @@ type Migrator struct {
 	checksum               Checksum
 	withChecksumValidation bool
 	withTx                 bool
+	txPerMigration         bool
 	reapplyAll             bool
+	outOfOrder             bool
+	withLocking            bool
+	withHistory            bool
+	splitStatements        bool
+	backslashEscapes       bool
+	lockTimeout            time.Duration
+	migrationTimeout       time.Duration
+	retryPolicy            RetryPolicy
+	hooks                  hooks
+	logger                 *slog.Logger
+
+	// lease is the migration lease held by the run, if any, see [Migrator.withLock].
+	lease *lease
 }
 
 type Opt func(*Migrator)
@@ -74,6 +89,7 @@ func New(db types.DBTX, dialect types.Dialect, opts ...Opt) *Migrator {
 		checksum:               normalizedSha1,
 		withChecksumValidation: true,
 		withTx:                 true,
+		logger:                 slog.New(slog.DiscardHandler),
 	}
 
 	for _, opt := range opts {
@@ -98,6 +114,20 @@ func WithTransaction(enabled bool) Opt {
 	}
 }
 
+// WithTransactionPerMigration controls whether each migration is applied
+// in its own transaction, instead of a single transaction for the whole run.
+//
+// When enabled, every migration is committed together with its schema version
+// as soon as it is applied. A failing migration only rolls back its own changes,
+// and the migrations applied before it remain committed.
+//
+// It has no effect if transactions are disabled using [WithTransaction].
+func WithTransactionPerMigration(enabled bool) Opt {
+	return func(m *Migrator) {
+		m.txPerMigration = enabled
+	}
+}
+
 func WithChecksumValidation(enabled bool) Opt {
 	return func(m *Migrator) {
 		m.withChecksumValidation = enabled
@@ -127,6 +157,111 @@ func WithReapplyAll(enabled bool) Opt {
 	}
 }
 
+// WithOutOfOrder controls whether [Migrator.ApplyVersioned] applies unapplied
+// migrations older than the latest applied migration, e.g., migrations merged late
+// from a long-lived branch. By default, such migrations fail the run with [ErrOutOfOrder].
+func WithOutOfOrder(enabled bool) Opt {
+	return func(m *Migrator) {
+		m.outOfOrder = enabled
+	}
+}
+
+// WithBackslashEscapes controls whether a backslash escapes the following character
+// within string literals when splitting statements without a dialect implementing
+// [types.StatementSplitter], as in MySQL, e.g., 'it\'s'. Disabled by default,
+// following standard SQL.
+//
+// See [WithStatementSplitting].
+func WithBackslashEscapes(enabled bool) Opt {
+	return func(m *Migrator) {
+		m.backslashEscapes = enabled
+	}
+}
+
+// WithHistory controls whether a history of the executed migrations is kept.
+//
+// When enabled, a row is recorded for every applied, reverted or failed migration,
+// including its checksum, start time, duration and outcome. Successful executions are
+// recorded in the same transaction as the schema version. The dialect must implement
+// [types.HistoryDialect].
+//
+// The recorded history can be read using [Migrator.History].
+func WithHistory(enabled bool) Opt {
+	return func(m *Migrator) {
+		m.withHistory = enabled
+	}
+}
+
+// WithStatementSplitting controls whether migration scripts are split into
+// individual statements, executed one at a time, instead of executing each
+// script as a whole. This is required by drivers that accept a single statement
+// per execution.
+//
+// Scripts are split using the dialect, if it implements [types.StatementSplitter].
+// Otherwise, scripts are split on semicolons outside of comments, string literals
+// and quoted identifiers, see [WithBackslashEscapes].
+//
+// When a statement fails, the returned [MigrationError] holds the failed
+// statement and its offset within the script.
+func WithStatementSplitting(enabled bool) Opt {
+	return func(m *Migrator) {
+		m.splitStatements = enabled
+	}
+}
+
+// WithLogger sets the logger used to report the progress of migration runs,
+// e.g., the schema version read, the validation result, and the start and finish
+// of each migration. By default, nothing is logged. A nil logger is ignored.
+func WithLogger(l *slog.Logger) Opt {
+	return func(m *Migrator) {
+		if l != nil {
+			m.logger = l
+		}
+	}
+}
+
+// WithLocking controls whether migration runs hold a lock
+// that serializes concurrent runs against the same database,
+// e.g., when several replicas of an application start at once.
+//
+// The lock is held for the whole run, from reading the current schema version
+// until all migrations are applied. The dialect must implement either [types.Locker]
+// or [types.LeaseLocker]. A lease left behind by a killed run is taken over once it expires.
+//
+// When the database is a [*sql.DB], the run is performed on a single
+// dedicated connection for as long as the lock is held.
+func WithLocking(enabled bool) Opt {
+	return func(m *Migrator) {
+		m.withLocking = enabled
+	}
+}
+
+// WithLockTimeout sets the maximum time to wait for the migration lock.
+// A zero or negative duration (default) waits until the context is done.
+//
+// See [WithLocking].
+func WithLockTimeout(d time.Duration) Opt {
+	return func(m *Migrator) {
+		m.lockTimeout = d
+	}
+}
+
+// WithMigrationTimeout sets the maximum time a single migration may take,
+// including its hooks and the saving of its schema version.
+// A zero or negative duration (default) only honors the context of the run.
+//
+// A script can override the timeout using the following directive comment:
+//
+//	-- migrate:timeout 30m
+//
+// A migration exceeding its timeout fails the run with an
+// error wrapping [ErrMigrationTimeout].
+func WithMigrationTimeout(d time.Duration) Opt {
+	return func(m *Migrator) {
+		m.migrationTimeout = d
+	}
+}
+
 func errf(format string, a ...any) error {
 	return fmt.Errorf(format, a...)
 }
@@ -149,6 +284,18 @@ func errf(format string, a ...any) error {
 //
 // With transactions enabled (default), any error triggers a rollback;
 // otherwise, migrations are applied sequentially until an error occurs or all are applied.
+// To commit each migration separately, use the [WithTransactionPerMigration] [Opt] function.
+//
+// A script containing statements that cannot run inside a transaction
+// (e.g., VACUUM) can opt out by starting with the following directive comment:
+//
+//	-- migrate:no-transaction
+//
+// Such a script is executed outside of any transaction. The migrations preceding it
+// are committed first, and the ones following it are applied in a new transaction.
+//
+// If the source implements [RepeatableLister], the changed repeatable migrations
+// are applied after the versioned migrations, within the same run.
 //
 // To reset the schema and force re-application of migrations,
 // along with re-generating checksum values, use the following:
@@ -159,66 +306,361 @@ func errf(format string, a ...any) error {
 //	}
 //	m := migrate.New(db, s.dialect, opts...)
 //	m.Apply(migrations)
+//
+// To accept an intentional edit of an applied migration
+// without re-applying any migrations, use [Migrator.Repair].
 func (m *Migrator) Apply(from Lister) (int, error) {
 	return m.ApplyContext(context.Background(), from)
 }
 
-func (m *Migrator) ApplyContext(ctx context.Context, from Lister) (int, error) {
-	migrations, err := from.List()
+func (m *Migrator) ApplyContext(ctx context.Context, from Lister) (n int, retErr error) {
+	defer func() {
+		retErr = m.onError(ctx, retErr)
+	}()
+
+	src, err := listSource(from)
+	if err != nil {
+		return 0, err
+	}
+
+	return m.withLock(ctx, func(lm *Migrator) (int, error) {
+		return lm.apply(ctx, src)
+	})
+}
+
+func (m *Migrator) apply(ctx context.Context, src source) (int, error) {
+	schema, runtimeChecksum, err := m.prepare(ctx, src.scripts)
+	if err != nil {
+		return 0, err
+	}
+
+	repeatable, err := m.repeatableSteps(ctx, src.repeatable)
+	if err != nil {
+		return 0, err
+	}
+
+	if !m.reapplyAll && schema.Version >= len(src.scripts) {
+		if len(repeatable) == 0 {
+			m.logger.InfoContext(ctx, "schema up to date", "version", schema.Version)
+			return 0, nil
+		}
+
+		return m.run(ctx, repeatable)
+	}
+
+	steps, err := m.applySteps(schema.Version, len(src.scripts), src, runtimeChecksum)
+	if err != nil {
+		return 0, err
+	}
+
+	return m.run(ctx, append(steps, repeatable...))
+}
+
+// Rollback reverts applied migrations in reverse order
+// until the schema reaches the target version.
+//
+// The down scripts are taken from the given [DownLister], where the i-th
+// down script reverts the i-th migration. The target version must be
+// between 0 and the current schema version. Rolling back to the current
+// version is a no-op.
+//
+// After each reverted migration, the schema version and its cumulative
+// checksum are updated to the values of the preceding version.
+// Migrations excluded by the [Filter] are not reverted, but the
+// recorded version is still updated past them.
+//
+// It returns the number of migrations reverted and any error encountered.
+// The transaction semantics are the same as for [Migrator.Apply].
+func (m *Migrator) Rollback(from DownLister, target int) (int, error) {
+	return m.RollbackContext(context.Background(), from, target)
+}
+
+func (m *Migrator) RollbackContext(ctx context.Context, from DownLister, target int) (n int, retErr error) {
+	defer func() {
+		retErr = m.onError(ctx, retErr)
+	}()
+
+	src, err := listSource(from)
+	if err != nil {
+		return 0, err
+	}
+
+	return m.withLock(ctx, func(lm *Migrator) (int, error) {
+		return lm.rollback(ctx, src, target)
+	})
+}
+
+func (m *Migrator) rollback(ctx context.Context, src source, target int) (int, error) {
+	schema, runtimeChecksum, err := m.prepare(ctx, src.scripts)
+	if err != nil {
+		return 0, err
+	}
+
+	if target < 0 || target > schema.Version {
+		return 0, errf("invalid rollback target %d: must be between 0 and the current version (%d)", target, schema.Version)
+	}
+
+	if target == schema.Version {
+		return 0, nil // already at target
+	}
+
+	steps, err := m.revertSteps(schema.Version, target, src, runtimeChecksum)
+	if err != nil {
+		return 0, err
+	}
+
+	return m.run(ctx, steps)
+}
+
+// MigrateTo moves the schema to the given target version.
+//
+// If the target is ahead of the current schema version, the migrations up
+// to and including the target are applied, as with [Migrator.Apply].
+// If the target is behind the current schema version, the source must
+// implement [DownLister], and the migrations are reverted as with [Migrator.Rollback].
+//
+// The target must be between 0 and the number of available migrations.
+//
+// It returns the number of migrations applied or reverted and any error encountered.
+func (m *Migrator) MigrateTo(from Lister, target int) (int, error) {
+	return m.MigrateToContext(context.Background(), from, target)
+}
+
+func (m *Migrator) MigrateToContext(ctx context.Context, from Lister, target int) (n int, retErr error) {
+	defer func() {
+		retErr = m.onError(ctx, retErr)
+	}()
+
+	src, err := listSource(from)
+	if err != nil {
+		return 0, err
+	}
+
+	if target < 0 || target > len(src.scripts) {
+		return 0, errf("invalid target version %d: must be between 0 and the number of available migrations (%d)", target, len(src.scripts))
+	}
+
+	return m.withLock(ctx, func(lm *Migrator) (int, error) {
+		return lm.migrateTo(ctx, src, target)
+	})
+}
+
+func (m *Migrator) migrateTo(ctx context.Context, src source, target int) (int, error) {
+	schema, runtimeChecksum, err := m.prepare(ctx, src.scripts)
+	if err != nil {
+		return 0, err
+	}
+
+	if target >= schema.Version {
+		if !m.reapplyAll && target == schema.Version {
+			return 0, nil // already at target
+		}
+
+		steps, err := m.applySteps(schema.Version, target, src, runtimeChecksum)
+		if err != nil {
+			return 0, err
+		}
+
+		return m.run(ctx, steps)
+	}
+
+	if src.downs == nil {
+		return 0, errf("migrate to version %d: current version is %d and the migrations source provides no down scripts", target, schema.Version)
+	}
+
+	steps, err := m.revertSteps(schema.Version, target, src, runtimeChecksum)
+	if err != nil {
+		return 0, err
+	}
+
+	return m.run(ctx, steps)
+}
+
+// prepare creates the schema version table if needed, reads the current
+// schema version and validates it against the given migrations.
+//
+// It returns the current schema version and the checksum history of the migrations.
+func (m *Migrator) prepare(ctx context.Context, migrations []string) (types.SchemaVersion, []string, error) {
+	schema, err := m.readVersion(ctx)
+	if err != nil {
+		return types.SchemaVersion{}, nil, err
+	}
+
+	runtimeChecksum, err := m.validate(ctx, schema, migrations)
 	if err != nil {
-		return 0, errf("list migrations source: %v", err)
+		return types.SchemaVersion{}, nil, err
 	}
 
+	return schema, runtimeChecksum, nil
+}
+
+// readVersion creates the schema version table if needed
+// and reads the current schema version.
+func (m *Migrator) readVersion(ctx context.Context) (types.SchemaVersion, error) {
 	if err := schemaops.CreateTable(ctx, m.db, m.dialect); err != nil {
-		return 0, errf("create schema version table: %v", err)
+		return types.SchemaVersion{}, errf("create schema version table: %w", err)
+	}
+
+	if m.withHistory {
+		if err := m.createHistoryTable(ctx); err != nil {
+			return types.SchemaVersion{}, err
+		}
 	}
 
 	schema, err := m.CurrentSchemaVersion(ctx)
 	if err != nil {
-		return 0, errf("current schema version: %v", err)
+		return types.SchemaVersion{}, errf("current schema version: %w", err)
 	}
 
+	m.logger.InfoContext(ctx, "schema version read", "version", schema.Version, "checksum", schema.Checksum)
+
+	return schema, nil
+}
+
+// validate checks the given schema version against the migrations
+// and returns the checksum history of the migrations.
+func (m *Migrator) validate(ctx context.Context, schema types.SchemaVersion, migrations []string) ([]string, error) {
 	if schema.Version > len(migrations) {
-		return 0, errf("database version (%d) exceeds available migrations (%d)", schema.Version, len(migrations))
+		m.logger.ErrorContext(ctx, "schema validation failed", "version", schema.Version, "migrations", len(migrations))
+		return nil, errf("%w: database version (%d), available migrations (%d)", ErrVersionAhead, schema.Version, len(migrations))
 	}
 
 	runtimeChecksum := m.checksumHistory(migrations)
 	if err := m.validateChecksum(schema, runtimeChecksum); err != nil {
-		return 0, errf("schema integrity check failed: %v", err)
+		m.logger.ErrorContext(ctx, "schema validation failed", "version", schema.Version, "error", err)
+		return nil, errf("schema integrity check failed: %w", err)
+	}
+
+	m.logger.InfoContext(ctx, "schema validated", "version", schema.Version, "checksum_validation", m.withChecksumValidation)
+
+	return runtimeChecksum, nil
+}
+
+// source holds the contents listed from a migrations source.
+type source struct {
+	// scripts are the listed migration scripts.
+	scripts []string
+
+	// downs are the listed down scripts, or nil if the source
+	// does not implement [DownLister].
+	downs []string
+
+	// funcs are the listed Go function migrations, or nil if the source
+	// does not implement [FuncLister].
+	funcs []Func
+
+	// repeatable are the listed repeatable migrations, or nil if the source
+	// does not implement [RepeatableLister].
+	repeatable []Repeatable
+
+	// migrations are the listed migrations along with their names and metadata,
+	// or nil if the source does not implement [MigrationLister].
+	migrations []Migration
+}
+
+// name returns the name of the migration of the given 1-based index, if known.
+func (s source) name(index int) string {
+	if s.migrations == nil {
+		return ""
 	}
 
-	if !m.reapplyAll && schema.Version >= len(migrations) {
-		return 0, nil // already up to date
+	return s.migrations[index-1].Name
+}
+
+// listSource lists the contents of the given migrations source,
+// including the contents provided by the optional listing interfaces.
+func listSource(from Lister) (source, error) {
+	if r, ok := from.(sourceResolver); ok {
+		return r.resolveSource()
 	}
 
-	if !m.withTx {
-		n, err := m.applyMigrations(ctx, m.db, schema.Version, migrations, runtimeChecksum)
+	scripts, err := from.List()
+	if err != nil {
+		return source{}, errf("list migrations source: %w", err)
+	}
+
+	src := source{scripts: scripts}
+
+	if dl, ok := from.(DownLister); ok {
+		downs, err := dl.ListDown()
 		if err != nil {
-			return n, errf("non-transactional migration: %w", err)
+			return source{}, errf("list down migrations source: %w", err)
+		}
+
+		if len(downs) != len(scripts) {
+			return source{}, errf("mismatched migrations and down migrations: expected %d down scripts, but found %d", len(scripts), len(downs))
 		}
 
-		return n, err
+		src.downs = downs
 	}
 
-	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{})
-	if err != nil {
-		return 0, errf("start transaction: %v", err)
+	if fl, ok := from.(FuncLister); ok {
+		funcs, err := fl.ListFuncs()
+		if err != nil {
+			return source{}, errf("list go migrations source: %w", err)
+		}
+
+		if len(funcs) != len(scripts) {
+			return source{}, errf("mismatched migrations and go migrations: expected %d functions, but found %d", len(scripts), len(funcs))
+		}
+
+		src.funcs = funcs
 	}
 
-	n, err := m.applyMigrations(ctx, tx, schema.Version, migrations, runtimeChecksum)
-	if err != nil {
-		if err2 := tx.Rollback(); err2 != nil {
-			return 0, errf("rollback: %v", errors.Join(err2, err))
+	if ml, ok := from.(MigrationLister); ok {
+		migrations, err := ml.ListMigrations()
+		if err != nil {
+			return source{}, errf("list named migrations source: %w", err)
 		}
 
-		return 0, err
+		if len(migrations) != len(scripts) {
+			return source{}, errf("mismatched migrations and named migrations: expected %d migrations, but found %d", len(scripts), len(migrations))
+		}
+
+		src.migrations = migrations
 	}
 
-	if err := tx.Commit(); err != nil {
-		return 0, errf("transaction commit: %v", err)
+	if rl, ok := from.(RepeatableLister); ok {
+		repeatable, err := rl.ListRepeatable()
+		if err != nil {
+			return source{}, errf("list repeatable migrations source: %w", err)
+		}
+
+		if err := validateRepeatable(repeatable); err != nil {
+			return source{}, err
+		}
+
+		src.repeatable = repeatable
+	}
+
+	return src, nil
+}
+
+// sourceResolver is implemented by migration sources that list
+// their contents at once, e.g., [CompositeMigrations], rather than
+// using each of the optional listing interfaces in turn.
+type sourceResolver interface {
+	resolveSource() (source, error)
+}
+
+// validateRepeatable validates that the given repeatable migrations
+// are identified by unique names.
+func validateRepeatable(repeatable []Repeatable) error {
+	names := make(map[string]bool, len(repeatable))
+
+	for i, r := range repeatable {
+		if r.Name == "" {
+			return errf("repeatable migration %d: missing name", i+1)
+		}
+
+		if names[r.Name] {
+			return errf("repeatable migration %q: duplicate name", r.Name)
+		}
+
+		names[r.Name] = true
 	}
 
-	return n, err
+	return nil
 }
 
 func (m *Migrator) CurrentSchemaVersion(ctx context.Context) (types.SchemaVersion, error) {
@@ -235,32 +677,25 @@ func (m *Migrator) CurrentSchemaVersion(ctx context.Context) (types.SchemaVersio
 	return types.SchemaVersion{}, nil
 }
 
-func (m *Migrator) applyMigrations(ctx context.Context, db types.CoreDB, current int, migrations []string, checksums []string) (n int, retErr error) {
-	if len(migrations)+1 != len(checksums) {
-		retErr = errf("mismatched migrations and checksums: expected %d checksums (+1 for initial state), but found %d", len(migrations), len(checksums))
-		return
-	}
-
+// pending returns the versions of the migrations to apply
+// when moving from the current version up to the target version.
+func (m *Migrator) pending(current int, target int) []int {
 	from := current
 	if m.reapplyAll {
 		from = 0
 	}
 
-	for i := from; i < len(migrations); i++ {
-		if !m.migrationFilter(i + 1) {
-			continue
-		}
+	var versions []int
 
-		sch := types.SchemaVersion{Version: i + 1, Checksum: checksums[i+1]}
-		if err := applyMigration(ctx, db, m.dialect, sch, migrations[i]); err != nil {
-			retErr = errf("apply migration script %d: %v", i+1, err)
-			return
+	for v := from + 1; v <= target; v++ {
+		if !m.migrationFilter(v) {
+			continue
 		}
 
-		n++
+		versions = append(versions, v)
 	}
 
-	return
+	return versions
 }
 
 func (m *Migrator) checksumHistory(migrations []string) []string {
@@ -284,28 +719,7 @@ func (m *Migrator) validateChecksum(schema types.SchemaVersion, runtimeChecksum
 	}
 
 	if schema.Checksum != runtimeChecksum[schema.Version] {
-		return errf("runtime checksum %q != database checksum %q", runtimeChecksum[schema.Version], schema.Checksum)
-	}
-
-	return nil
-}
-
-func applyMigration(ctx context.Context, db types.CoreDB, dialect types.Dialect, schema types.SchemaVersion, migration string) error {
-	if err := execContext(ctx, db, migration); err != nil {
-		return err
-	}
-
-	if err := schemaops.SaveVersion(ctx, db, dialect, schema); err != nil {
-		//nolint:wrapcheck // error is returned from an internal package
-		return err
-	}
-
-	return nil
-}
-
-func execContext(ctx context.Context, db types.CoreDB, query string, args ...any) error {
-	if _, err := db.ExecContext(ctx, query, args...); err != nil {
-		return fmt.Errorf("exec context: %v", err)
+		return errf("%w: runtime checksum %q != database checksum %q", ErrChecksumMismatch, runtimeChecksum[schema.Version], schema.Checksum)
 	}
 
 	return nil
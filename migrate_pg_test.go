@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	_ "github.com/jackc/pgx/v5/stdlib"
 
 	"github.com/testcontainers/testcontainers-go"
@@ -45,7 +46,7 @@ func postgresTestContainer(ctx context.Context) (*postgres.PostgresContainer, er
 	return ctr, nil
 }
 
-func setupPostgresTestSuite(ctx context.Context, t *testing.T, rawMigrations []string, embeddedMigrations migrate.EmbeddedMigrations) (*testSuite, func()) {
+func setupPostgresTestSuite(ctx context.Context, t *testing.T, rawMigrations []string, rawDownMigrations []string, embeddedMigrations migrate.EmbeddedMigrations) (*testSuite, func()) {
 	t.Helper()
 
 	ctr, err := postgresTestContainer(ctx)
@@ -86,6 +87,7 @@ func setupPostgresTestSuite(ctx context.Context, t *testing.T, rawMigrations []s
 		dialect:            migrate.PostgreSQLDialect{},
 		embeddedMigrations: embeddedMigrations,
 		rawMigrations:      rawMigrations,
+		rawDownMigrations:  rawDownMigrations,
 	})
 	if err != nil {
 		t.Fatalf("create test suite: %v", err)
@@ -112,7 +114,12 @@ func TestMigrateWithPostgres(t *testing.T) {
 		`,
 	}
 
-	suite, cleanup := setupPostgresTestSuite(t.Context(), t, rawMigrations, embeddedPostgresMigrations)
+	rawDownMigrations := []string{
+		`DROP TABLE testing_migration_1;`,
+		`DROP TABLE testing_migration_2;`,
+	}
+
+	suite, cleanup := setupPostgresTestSuite(t.Context(), t, rawMigrations, rawDownMigrations, embeddedPostgresMigrations)
 	defer cleanup()
 
 	t.Run("TestDialect", func(t *testing.T) {
@@ -121,12 +128,77 @@ func TestMigrateWithPostgres(t *testing.T) {
 		}
 	})
 
+	t.Run("TestHistoryDialect", func(t *testing.T) {
+		if err := migratetest.TestHistoryDialect(t.Context(), suite.dbHelper(t.Context(), t), migrate.PostgreSQLDialect{}); err != nil {
+			t.Fatalf("TestHistoryDialect: %v", err)
+		}
+	})
+
+	t.Run("TestRepeatableDialect", func(t *testing.T) {
+		if err := migratetest.TestRepeatableDialect(t.Context(), suite.dbHelper(t.Context(), t), migrate.PostgreSQLDialect{}); err != nil {
+			t.Fatalf("TestRepeatableDialect: %v", err)
+		}
+	})
+
+	t.Run("TestAppliedVersionsDialect", func(t *testing.T) {
+		if err := migratetest.TestAppliedVersionsDialect(t.Context(), suite.dbHelper(t.Context(), t), migrate.PostgreSQLDialect{}); err != nil {
+			t.Fatalf("TestAppliedVersionsDialect: %v", err)
+		}
+	})
+
 	t.Run("ApplyStringMigrations", suite.applyStringMigrations)
 	t.Run("ApplyEmbeddedMigrations", suite.applyEmbeddedMigrations)
+	t.Run("ApplyFSMigrations", suite.applyFSMigrations)
+	t.Run("ApplyGoMigrations", suite.applyGoMigrations)
+	t.Run("ApplyNamedMigrations", suite.applyNamedMigrations)
+	t.Run("ApplyCompositeMigrations", suite.applyCompositeMigrations)
 	t.Run("ApplyWithTxDisabled", suite.applyWithTxDisabled)
+	t.Run("ApplyWithTxPerMigration", suite.applyWithTxPerMigration)
+	t.Run("ApplyWithNoTxDirective", suite.applyWithNoTxDirective)
+	t.Run("ApplyWithLocking", suite.applyWithLocking)
+	t.Run("ApplyWithStaleLease", suite.applyWithStaleLease)
+	t.Run("FailsLockingOnNonLockErrors", suite.failsLockingOnNonLockErrors)
+	t.Run("ApplyWithHistory", suite.applyWithHistory)
+	t.Run("ApplyWithHooks", suite.applyWithHooks)
+	t.Run("ApplyWithLogger", suite.applyWithLogger)
+	t.Run("ApplyWithStatementSplitting", suite.applyWithStatementSplitting)
+	t.Run("ApplyWithMigrationTimeout", suite.applyWithMigrationTimeout)
+	t.Run("ApplyWithRetry", suite.applyWithRetry)
+	t.Run("ApplyRepeatable", suite.applyRepeatable)
+	t.Run("ApplyRepeatableWrapped", suite.applyRepeatableWrapped)
 	t.Run("ApplyWithNoChecksumValidation", suite.applyWithNoChecksumValidation)
 	t.Run("ApplyWithFilter", suite.applyWithFilter)
 	t.Run("ReapplyAll", suite.reapplyAll)
 	t.Run("RollsBackOnSQLError", suite.rollsBackOnSQLError)
 	t.Run("RollsBackOnValidationError", suite.rollsBackOnValidationError)
+	t.Run("FailsOnVersionAhead", suite.failsOnVersionAhead)
+	t.Run("Rollback", suite.rollback)
+	t.Run("RollbackIrreversible", suite.rollbackIrreversible)
+	t.Run("RollbackFSMigrations", suite.rollbackFSMigrations)
+	t.Run("MigrateTo", suite.migrateTo)
+	t.Run("Plan", suite.plan)
+	t.Run("Baseline", suite.baseline)
+	t.Run("RepairAndForceVersion", suite.repairAndForceVersion)
+	t.Run("Status", suite.status)
+	t.Run("ApplyVersioned", suite.applyVersioned)
+}
+
+func TestPostgreSQLDialectIsTransient(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{code: "55P03", want: true},
+		{code: "40001", want: true},
+		{code: "40P01", want: true},
+		{code: "42P01", want: false},
+	}
+
+	for _, tt := range tests {
+		err := fmt.Errorf("exec context: %w", &pgconn.PgError{Code: tt.code})
+
+		if got := (migrate.PostgreSQLDialect{}).IsTransient(err); got != tt.want {
+			t.Errorf("IsTransient(%s) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
 }
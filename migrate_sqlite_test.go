@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"embed"
+	"errors"
+	"path/filepath"
 	"testing"
 
 	_ "modernc.org/sqlite"
@@ -22,12 +24,15 @@ var (
 	}
 )
 
-// createSQLiteDB is a testing helper that creates an in-memory sqlite
+// createSQLiteDB is a testing helper that creates a temporary sqlite
 // database connection.
+//
+// A file backed database is used, since every connection to an in-memory
+// database opens a separate database.
 func createSQLiteDB(_ context.Context, t *testing.T) *sql.DB {
 	t.Helper()
 
-	db, err := sql.Open("sqlite", ":memory:")
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
 	if err != nil {
 		t.Fatalf("Failed to open database: %v", err)
 	}
@@ -55,11 +60,17 @@ func TestMigrateWithSQLite(t *testing.T) {
 		`,
 	}
 
+	rawDownMigrations := []string{
+		`DROP TABLE testing_migration_1;`,
+		`DROP TABLE testing_migration_2;`,
+	}
+
 	suite, err := newTestSuite(testSuiteConfig{
 		dbHelper:           createSQLiteDB,
 		dialect:            migrate.SQLiteDialect{},
 		embeddedMigrations: embeddedSQLiteMigrations,
 		rawMigrations:      rawMigrations,
+		rawDownMigrations:  rawDownMigrations,
 	})
 	if err != nil {
 		t.Fatalf("create test suite: %v", err)
@@ -71,12 +82,104 @@ func TestMigrateWithSQLite(t *testing.T) {
 		}
 	})
 
+	t.Run("TestHistoryDialect", func(t *testing.T) {
+		if err := migratetest.TestHistoryDialect(t.Context(), suite.dbHelper(t.Context(), t), migrate.SQLiteDialect{}); err != nil {
+			t.Fatalf("TestHistoryDialect: %v", err)
+		}
+	})
+
+	t.Run("TestRepeatableDialect", func(t *testing.T) {
+		if err := migratetest.TestRepeatableDialect(t.Context(), suite.dbHelper(t.Context(), t), migrate.SQLiteDialect{}); err != nil {
+			t.Fatalf("TestRepeatableDialect: %v", err)
+		}
+	})
+
+	t.Run("TestAppliedVersionsDialect", func(t *testing.T) {
+		if err := migratetest.TestAppliedVersionsDialect(t.Context(), suite.dbHelper(t.Context(), t), migrate.SQLiteDialect{}); err != nil {
+			t.Fatalf("TestAppliedVersionsDialect: %v", err)
+		}
+	})
+
+	t.Run("TestLeaseLocker", func(t *testing.T) {
+		if err := migratetest.TestLeaseLocker(t.Context(), suite.dbHelper(t.Context(), t), migrate.SQLiteDialect{}); err != nil {
+			t.Fatalf("TestLeaseLocker: %v", err)
+		}
+	})
+
 	t.Run("ApplyStringMigrations", suite.applyStringMigrations)
 	t.Run("ApplyEmbeddedMigrations", suite.applyEmbeddedMigrations)
+	t.Run("ApplyFSMigrations", suite.applyFSMigrations)
+	t.Run("ApplyGoMigrations", suite.applyGoMigrations)
+	t.Run("ApplyNamedMigrations", suite.applyNamedMigrations)
+	t.Run("ApplyCompositeMigrations", suite.applyCompositeMigrations)
 	t.Run("ApplyWithTxDisabled", suite.applyWithTxDisabled)
+	t.Run("ApplyWithTxPerMigration", suite.applyWithTxPerMigration)
+	t.Run("ApplyWithNoTxDirective", suite.applyWithNoTxDirective)
+	t.Run("ApplyWithLocking", suite.applyWithLocking)
+	t.Run("ApplyWithStaleLease", suite.applyWithStaleLease)
+	t.Run("FailsLockingOnNonLockErrors", suite.failsLockingOnNonLockErrors)
+	t.Run("ApplyWithHistory", suite.applyWithHistory)
+	t.Run("ApplyWithHooks", suite.applyWithHooks)
+	t.Run("ApplyWithLogger", suite.applyWithLogger)
+	t.Run("ApplyWithStatementSplitting", suite.applyWithStatementSplitting)
+	t.Run("ApplyWithMigrationTimeout", suite.applyWithMigrationTimeout)
+	t.Run("ApplyWithRetry", suite.applyWithRetry)
+	t.Run("ApplyRepeatable", suite.applyRepeatable)
+	t.Run("ApplyRepeatableWrapped", suite.applyRepeatableWrapped)
 	t.Run("ApplyWithNoChecksumValidation", suite.applyWithNoChecksumValidation)
 	t.Run("ApplyWithFilter", suite.applyWithFilter)
 	t.Run("ReapplyAll", suite.reapplyAll)
 	t.Run("RollsBackOnSQLError", suite.rollsBackOnSQLError)
 	t.Run("RollsBackOnValidationError", suite.rollsBackOnValidationError)
+	t.Run("FailsOnVersionAhead", suite.failsOnVersionAhead)
+	t.Run("Rollback", suite.rollback)
+	t.Run("RollbackIrreversible", suite.rollbackIrreversible)
+	t.Run("RollbackFSMigrations", suite.rollbackFSMigrations)
+	t.Run("MigrateTo", suite.migrateTo)
+	t.Run("Plan", suite.plan)
+	t.Run("Baseline", suite.baseline)
+	t.Run("RepairAndForceVersion", suite.repairAndForceVersion)
+	t.Run("Status", suite.status)
+	t.Run("ApplyVersioned", suite.applyVersioned)
+}
+
+func TestSQLiteDialectIsTransient(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	open := func() *sql.DB {
+		db, err := sql.Open("sqlite", path)
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+
+		t.Cleanup(func() { _ = db.Close() })
+
+		return db
+	}
+
+	locker, db := open(), open()
+
+	conn, err := locker.Conn(t.Context())
+	if err != nil {
+		t.Fatalf("get connection: %v", err)
+	}
+
+	t.Cleanup(func() { _ = conn.Close() })
+
+	if _, err := conn.ExecContext(t.Context(), "BEGIN IMMEDIATE;"); err != nil {
+		t.Fatalf("begin immediate transaction: %v", err)
+	}
+
+	_, err = db.ExecContext(t.Context(), "CREATE TABLE locked (id INTEGER);")
+	if err == nil {
+		t.Fatal("expected an error but got none")
+	}
+
+	if !(migrate.SQLiteDialect{}).IsTransient(err) {
+		t.Errorf("IsTransient(%v) = false, want true", err)
+	}
+
+	if (migrate.SQLiteDialect{}).IsTransient(errors.New("not an sqlite error")) {
+		t.Error("IsTransient() = true for a non sqlite error, want false")
+	}
 }
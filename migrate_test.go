@@ -1,12 +1,19 @@
 package migrate_test
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
+	"slices"
 	"strings"
 	"testing"
+	"testing/fstest"
+	"time"
 
 	"github.com/ladzaretti/migrate"
 	"github.com/ladzaretti/migrate/types"
@@ -17,6 +24,7 @@ type testSuiteConfig struct {
 	dialect            types.Dialect
 	embeddedMigrations migrate.EmbeddedMigrations
 	rawMigrations      []string
+	rawDownMigrations  []string
 }
 
 type testSuite struct {
@@ -28,6 +36,10 @@ func newTestSuite(conf testSuiteConfig) (*testSuite, error) {
 		return nil, errors.New("stringMigrations must have at least 2 elements")
 	}
 
+	if len(conf.rawDownMigrations) != len(conf.rawMigrations) {
+		return nil, errors.New("rawDownMigrations must match rawMigrations in length")
+	}
+
 	embeddedMigrations, err := conf.embeddedMigrations.List()
 	if err != nil {
 		return nil, fmt.Errorf("list embedded migrations: %w", err)
@@ -112,6 +124,267 @@ func (s *testSuite) applyEmbeddedMigrations(t *testing.T) {
 	}
 }
 
+func (s *testSuite) applyFSMigrations(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect)
+
+	fsys := fstest.MapFS{
+		"migrations/02_migration.sql":      {Data: []byte(s.rawMigrations[1])},
+		"migrations/01_migration.sql":      {Data: []byte(s.rawMigrations[0])},
+		"migrations/nested/03_ignored.sql": {Data: []byte("invalid sql")},
+	}
+
+	migrations := migrate.FSMigrations{FS: fsys, Path: "migrations"}
+
+	scripts, err := migrations.List()
+	if err != nil {
+		t.Fatalf("migrations.List() returned an error: %v", err)
+	}
+
+	if got, want := scripts, s.rawMigrations; !slices.Equal(got, want) {
+		t.Errorf("listed migrations mismatch: got %q, want %q", got, want)
+	}
+
+	n, err := m.Apply(migrations)
+	if err != nil {
+		t.Errorf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, len(s.rawMigrations); got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	if _, err := (migrate.FSMigrations{FS: fsys, Path: "missing"}).List(); err == nil {
+		t.Error("expected an error but got none")
+	}
+}
+
+func TestFSMigrationsOrdering(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/10_third.sql":  {Data: []byte("third")},
+		"migrations/09_second.sql": {Data: []byte("second")},
+		"migrations/1_first.sql":   {Data: []byte("first")},
+	}
+
+	scripts, err := migrate.FSMigrations{FS: fsys, Path: "migrations"}.List()
+	if err != nil {
+		t.Fatalf("List() returned an error: %v", err)
+	}
+
+	if got, want := scripts, []string{"first", "second", "third"}; !slices.Equal(got, want) {
+		t.Errorf("listed migrations mismatch: got %q, want %q", got, want)
+	}
+
+	versioned, err := migrate.FSMigrations{FS: fsys, Path: "migrations"}.ListVersioned()
+	if err != nil {
+		t.Fatalf("ListVersioned() returned an error: %v", err)
+	}
+
+	if got, want := versioned[2], (migrate.VersionedScript{Version: 10, Name: "10_third.sql", SQL: "third"}); got != want {
+		t.Errorf("versioned migration mismatch: got %v, want %v", got, want)
+	}
+
+	named, err := migrate.FSMigrations{FS: fsys, Path: "migrations"}.ListMigrations()
+	if err != nil {
+		t.Fatalf("ListMigrations() returned an error: %v", err)
+	}
+
+	if got, want := named[1].Source, "migrations/09_second.sql"; got != want {
+		t.Errorf("migration source mismatch: got %q, want %q", got, want)
+	}
+
+	tests := []struct {
+		name  string
+		files []string
+		gaps  bool
+		want  error
+	}{
+		{name: "duplicate", files: []string{"1_a.sql", "01_b.sql"}, want: migrate.ErrDuplicateVersion},
+		{name: "invalid", files: []string{"1_a.sql", "readme.md"}, want: migrate.ErrInvalidFileName},
+		{name: "gap allowed", files: []string{"1_a.sql", "3_b.sql"}},
+		{name: "gap disallowed", files: []string{"1_a.sql", "3_b.sql"}, gaps: true, want: migrate.ErrVersionGap},
+		{name: "consecutive", files: []string{"1_a.sql", "2_b.sql"}, gaps: true},
+		{name: "paired", files: []string{"1_a.up.sql", "1_a.down.sql", "2_b.sql"}, gaps: true},
+		{name: "orphan down", files: []string{"1_a.up.sql", "2_b.down.sql"}, want: migrate.ErrOrphanDown},
+		{name: "duplicate down", files: []string{"1_a.up.sql", "1_a.down.sql", "01_b.down.sql"}, want: migrate.ErrDuplicateVersion},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{}
+			for _, f := range tt.files {
+				fsys["migrations/"+f] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+			}
+
+			_, err := migrate.FSMigrations{FS: fsys, Path: "migrations", DisallowGaps: tt.gaps}.List()
+			if !errors.Is(err, tt.want) {
+				t.Errorf("unexpected error: got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func (s *testSuite) applyNamedMigrations(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect)
+
+	migrations := migrate.Migrations{
+		{Name: "create_first", SQL: s.rawMigrations[0]},
+		{Name: "create_second", SQL: s.rawMigrations[1]},
+		{Name: "broken", SQL: "invalid sql"},
+	}
+
+	_, err := m.Apply(migrate.AsLister(migrations))
+
+	var me *migrate.MigrationError
+	if !errors.As(err, &me) {
+		t.Fatalf("unexpected error: got %v, want a *migrate.MigrationError", err)
+	}
+
+	if got, want := me.Name, "broken"; got != want {
+		t.Errorf("failed migration name mismatch: got %q, want %q", got, want)
+	}
+
+	if !strings.Contains(err.Error(), "(broken)") {
+		t.Errorf("error does not name the failed migration: %v", err)
+	}
+
+	statuses, err := m.Status(migrations[:2])
+	if err != nil {
+		t.Fatalf("m.Status() returned an error: %v", err)
+	}
+
+	for i, st := range statuses {
+		if got, want := st.Name, migrations[i].Name; got != want {
+			t.Errorf("status name mismatch: got %q, want %q", got, want)
+		}
+	}
+}
+
+func (s *testSuite) applyCompositeMigrations(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect)
+
+	migrations := migrate.CompositeMigrations{
+		Sources: []migrate.Lister{
+			pairedMigrationsFrom(s.rawMigrations[:1], s.rawDownMigrations[:1]),
+			stringMigrationsFrom(s.rawMigrations[1:]...),
+		},
+	}
+
+	n, err := m.Apply(migrations)
+	if err != nil {
+		t.Errorf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, len(s.rawMigrations); got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	// the migrations of the second source have no down scripts
+	if _, err := m.Rollback(migrations, 0); err == nil {
+		t.Error("expected an error but got none")
+	}
+
+	n, err = m.Rollback(migrate.CompositeMigrations{Sources: []migrate.Lister{pairedMigrationsFrom(s.rawMigrations, s.rawDownMigrations)}}, 0)
+	if err != nil {
+		t.Errorf("m.Rollback() returned an error: %v", err)
+	}
+
+	if got, want := n, len(s.rawMigrations); got != want {
+		t.Errorf("reverted migrations: got %d, want %d", got, want)
+	}
+
+	// each source is listed once per run, as if applied on its own
+	//
+
+	fsys := &countingFS{FS: fstest.MapFS{
+		"migrations/01_migration.sql": {Data: []byte(s.rawMigrations[0])},
+		"migrations/02_migration.sql": {Data: []byte(s.rawMigrations[1])},
+	}}
+
+	if _, err := m.Apply(migrate.FSMigrations{FS: fsys, Path: "migrations"}); err != nil {
+		t.Errorf("m.Apply() returned an error: %v", err)
+	}
+
+	want := fsys.opened
+	fsys.opened = 0
+
+	if _, err := m.Apply(migrate.CompositeMigrations{Sources: []migrate.Lister{migrate.FSMigrations{FS: fsys, Path: "migrations"}}}); err != nil {
+		t.Errorf("m.Apply() returned an error: %v", err)
+	}
+
+	if got := fsys.opened; got != want {
+		t.Errorf("opened files: got %d, want %d", got, want)
+	}
+}
+
+// countingFS is an [fs.FS] counting the opened files.
+type countingFS struct {
+	fs.FS
+	opened int
+}
+
+func (c *countingFS) Open(name string) (fs.File, error) {
+	c.opened++
+	return c.FS.Open(name)
+}
+
+func TestCompositeMigrations(t *testing.T) {
+	core := fstest.MapFS{
+		"migrations/1_core.sql": {Data: []byte("core 1")},
+		"migrations/3_core.sql": {Data: []byte("core 3")},
+	}
+
+	plugin := migrate.Migrations{
+		{Version: 2, Name: "plugin", SQL: "plugin 2"},
+	}
+
+	tests := []struct {
+		name    string
+		sources []migrate.Lister
+		order   migrate.MergeOrder
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "concatenate",
+			sources: []migrate.Lister{migrate.FSMigrations{FS: core, Path: "migrations"}, plugin, stringMigrationsFrom("extra")},
+			want:    []string{"core 1", "core 3", "plugin 2", "extra"},
+		},
+		{
+			name:    "interleave by version",
+			sources: []migrate.Lister{migrate.FSMigrations{FS: core, Path: "migrations"}, plugin},
+			order:   migrate.InterleaveByVersion,
+			want:    []string{"core 1", "plugin 2", "core 3"},
+		},
+		{
+			name:    "interleave unversioned",
+			sources: []migrate.Lister{plugin, stringMigrationsFrom("extra")},
+			order:   migrate.InterleaveByVersion,
+			wantErr: true,
+		},
+		{
+			name:    "version collision",
+			sources: []migrate.Lister{migrate.FSMigrations{FS: core, Path: "migrations"}, migrate.Migrations{{Version: 3, SQL: "plugin 3"}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := migrate.CompositeMigrations{Sources: tt.sources, Order: tt.order}.List()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("List() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("listed migrations mismatch: got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func (s *testSuite) applyWithTxDisabled(t *testing.T) {
 	db := s.dbHelper(t.Context(), t)
 
@@ -262,13 +535,18 @@ func (s *testSuite) applyWithFilter(t *testing.T) {
 	}
 }
 
-func (s *testSuite) reapplyAll(t *testing.T) {
+func (s *testSuite) applyWithTxPerMigration(t *testing.T) {
 	db := s.dbHelper(t.Context(), t)
-	m := migrate.New(db, s.dialect)
+	opts := []migrate.Opt{
+		migrate.WithTransactionPerMigration(true),
+	}
+	m := migrate.New(db, s.dialect, opts...)
 
-	n, err := m.Apply(stringMigrationsFrom(s.rawMigrations...))
-	if err != nil {
-		t.Errorf("m.Apply() returned an error: %v", err)
+	corrupted := copyAppend(s.rawMigrations, "invalid migration script")
+
+	n, err := m.Apply(stringMigrationsFrom(corrupted...))
+	if err == nil {
+		t.Error("expected an error but got none")
 	}
 
 	if got, want := n, len(s.rawMigrations); got != want {
@@ -279,79 +557,95 @@ func (s *testSuite) reapplyAll(t *testing.T) {
 		t.Errorf("schema version mismatch: got %v, want %v", got, want)
 	}
 
-	opts := []migrate.Opt{
-		migrate.WithReapplyAll(true),
-	}
-	m = migrate.New(db, s.dialect, opts...)
-
 	n, err = m.Apply(stringMigrationsFrom(s.rawMigrations...))
 	if err != nil {
 		t.Errorf("m.Apply() returned an error: %v", err)
 	}
 
-	if got, want := n, len(s.rawMigrations); got != want {
+	if got, want := n, 0; got != want {
 		t.Errorf("applied migrations: got %d, want %d", got, want)
 	}
-
-	if got, want := currentSchemaVersion(m), len(s.rawMigrations); got != want {
-		t.Errorf("schema version mismatch: got %v, want %v", got, want)
-	}
 }
 
-func (s *testSuite) rollsBackOnSQLError(t *testing.T) {
+func (s *testSuite) applyWithNoTxDirective(t *testing.T) {
 	db := s.dbHelper(t.Context(), t)
 	m := migrate.New(db, s.dialect)
 
-	if got, want := currentSchemaVersion(m), -1; got != want {
-		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	// VACUUM cannot run inside a transaction
+	//
+
+	n, err := m.Apply(stringMigrationsFrom(copyAppend(s.rawMigrations, "VACUUM;")...))
+	if err == nil {
+		t.Error("expected an error but got none")
 	}
 
-	n, err := m.Apply(stringMigrationsFrom(s.rawMigrations[0]))
+	if got, want := n, 0; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	migrations := copyAppend(s.rawMigrations, "-- migrate:no-transaction\nVACUUM;")
+
+	n, err = m.Apply(stringMigrationsFrom(migrations...))
 	if err != nil {
 		t.Errorf("m.Apply() returned an error: %v", err)
 	}
 
-	if got, want := n, 1; got != want {
+	if got, want := n, len(migrations); got != want {
 		t.Errorf("applied migrations: got %d, want %d", got, want)
 	}
 
-	if got, want := currentSchemaVersion(m), 1; got != want {
+	if got, want := currentSchemaVersion(m), len(migrations); got != want {
 		t.Errorf("schema version mismatch: got %v, want %v", got, want)
 	}
 
-	// run corrupted migration
+	// unknown directives are rejected
 	//
 
-	corrupted := copyAppend(s.rawMigrations, "invalid migration script")
-
-	n, err = m.Apply(stringMigrationsFrom(corrupted...))
+	_, err = m.Apply(stringMigrationsFrom(copyAppend(migrations, "-- migrate:no-transactions\nVACUUM;")...))
 	if err == nil {
 		t.Error("expected an error but got none")
 	}
+}
 
-	if got, want := n, 0; got != want {
-		t.Errorf("applied migrations: got %d, want %d", got, want)
+func (s *testSuite) applyWithLocking(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+
+	// hold the lock from another session
+	//
+
+	conn, err := db.Conn(t.Context())
+	if err != nil {
+		t.Fatalf("db.Conn() returned an error: %v", err)
 	}
 
-	gotErr, wantPrefix := err.Error(), `apply migration script 3: exec context:`
-	if !strings.HasPrefix(gotErr, wantPrefix) {
-		t.Errorf("unexpected error: got %q, want prefix %q", gotErr, wantPrefix)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	lock, unlock := s.lockQueries(t)
+
+	if err := lock(conn); err != nil {
+		t.Fatalf("acquire lock: %v", err)
 	}
 
-	if got, want := currentSchemaVersion(m), 1; got != want {
-		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	opts := []migrate.Opt{
+		migrate.WithLocking(true),
+		migrate.WithLockTimeout(300 * time.Millisecond),
 	}
-}
+	m := migrate.New(db, s.dialect, opts...)
 
-func (s *testSuite) rollsBackOnValidationError(t *testing.T) {
-	db := s.dbHelper(t.Context(), t)
-	m := migrate.New(db, s.dialect)
+	n, err := m.Apply(stringMigrationsFrom(s.rawMigrations...))
+	if err == nil {
+		t.Error("expected an error but got none")
+	}
 
-	if got, want := currentSchemaVersion(m), -1; got != want {
-		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	if got, want := n, 0; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
 	}
 
-	n, err := m.Apply(stringMigrationsFrom(s.rawMigrations...))
+	if err := unlock(conn); err != nil {
+		t.Fatalf("release lock: %v", err)
+	}
+
+	n, err = m.Apply(stringMigrationsFrom(s.rawMigrations...))
 	if err != nil {
 		t.Errorf("m.Apply() returned an error: %v", err)
 	}
@@ -364,49 +658,1586 @@ func (s *testSuite) rollsBackOnValidationError(t *testing.T) {
 		t.Errorf("schema version mismatch: got %v, want %v", got, want)
 	}
 
-	// run the same migration again
+	// the lock is released once the run is over
 	//
 
-	n, err = m.Apply(stringMigrationsFrom(s.rawMigrations...))
+	if err := lock(conn); err != nil {
+		t.Errorf("acquire lock: %v", err)
+	}
+}
+
+// lockQueries returns functions acquiring and releasing the migration lock
+// of the dialect under test on behalf of another session.
+func (s *testSuite) lockQueries(t *testing.T) (lock func(types.CoreDB) error, unlock func(types.CoreDB) error) {
+	t.Helper()
+
+	switch d := s.dialect.(type) {
+	case types.Locker:
+		lock = func(db types.CoreDB) error {
+			_, err := db.ExecContext(t.Context(), d.LockQuery())
+			return err
+		}
+		unlock = func(db types.CoreDB) error {
+			_, err := db.ExecContext(t.Context(), d.UnlockQuery())
+			return err
+		}
+	case types.LeaseLocker:
+		const owner = "another session"
+
+		lock = func(db types.CoreDB) error {
+			if _, err := db.ExecContext(t.Context(), d.CreateLockTableQuery()); err != nil {
+				return err
+			}
+
+			now := time.Now()
+
+			res, err := db.ExecContext(t.Context(), d.AcquireLockQuery(), owner, now.Add(time.Hour).UnixMilli(), now.UnixMilli())
+			if err != nil {
+				return err
+			}
+
+			if n, err := res.RowsAffected(); err != nil || n != 1 {
+				return fmt.Errorf("lease not acquired: %d rows affected: %w", n, err)
+			}
+
+			return nil
+		}
+		unlock = func(db types.CoreDB) error {
+			_, err := db.ExecContext(t.Context(), d.ReleaseLockQuery(), owner)
+			return err
+		}
+	default:
+		t.Skipf("dialect %T does not implement types.Locker or types.LeaseLocker", s.dialect)
+	}
+
+	return lock, unlock
+}
+
+func (s *testSuite) applyWithStaleLease(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+
+	leaser, ok := s.dialect.(types.LeaseLocker)
+	if !ok {
+		t.Skipf("dialect %T does not implement types.LeaseLocker", s.dialect)
+	}
+
+	// a lease left behind by a run killed mid-migration
+	//
+
+	if _, err := db.ExecContext(t.Context(), leaser.CreateLockTableQuery()); err != nil {
+		t.Fatalf("create lock table: %v", err)
+	}
+
+	expired := time.Now().Add(-time.Minute)
+
+	if _, err := db.ExecContext(t.Context(), leaser.AcquireLockQuery(), "killed", expired.UnixMilli(), expired.UnixMilli()); err != nil {
+		t.Fatalf("acquire lease: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 2*time.Second)
+	defer cancel()
+
+	m := migrate.New(db, s.dialect, migrate.WithLocking(true))
+
+	n, err := m.ApplyContext(ctx, stringMigrationsFrom(s.rawMigrations...))
 	if err != nil {
-		t.Errorf("m.Apply() returned an error: %v", err)
+		t.Fatalf("m.ApplyContext() returned an error: %v", err)
 	}
 
-	if got, want := n, 0; got != want {
+	if got, want := n, len(s.rawMigrations); got != want {
 		t.Errorf("applied migrations: got %d, want %d", got, want)
 	}
+}
 
-	if got, want := currentSchemaVersion(m), len(s.rawMigrations); got != want {
-		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+func (s *testSuite) failsLockingOnNonLockErrors(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+
+	if _, ok := s.dialect.(types.LeaseLocker); !ok {
+		t.Skipf("dialect %T does not implement types.LeaseLocker", s.dialect)
 	}
 
-	corrupted := copyAppend(s.rawMigrations)
-	corrupted[len(corrupted)-1] += "this string wasn't here before"
+	// a conflicting table prevents creating the lease table
+	if _, err := db.ExecContext(t.Context(), `CREATE VIEW schema_lock_lease AS SELECT 1 AS id;`); err != nil {
+		t.Fatalf("create view: %v", err)
+	}
 
-	// run corrupted migration
-	//
+	ctx, cancel := context.WithTimeout(t.Context(), 2*time.Second)
+	defer cancel()
 
-	n, err = m.Apply(stringMigrationsFrom(corrupted...))
+	m := migrate.New(db, s.dialect, migrate.WithLocking(true))
+
+	_, err := m.ApplyContext(ctx, stringMigrationsFrom(s.rawMigrations...))
 	if err == nil {
+		t.Fatal("expected an error but got none")
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("non-lock error was retried until the deadline: %v", err)
+	}
+}
+
+func (s *testSuite) applyWithHistory(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	opts := []migrate.Opt{
+		migrate.WithHistory(true),
+	}
+	m := migrate.New(db, s.dialect, opts...)
+
+	migrations := pairedMigrationsFrom(s.rawMigrations, s.rawDownMigrations)
+
+	if _, err := m.Apply(migrations); err != nil {
+		t.Errorf("m.Apply() returned an error: %v", err)
+	}
+
+	if _, err := m.Rollback(migrations, len(s.rawMigrations)-1); err != nil {
+		t.Errorf("m.Rollback() returned an error: %v", err)
+	}
+
+	corrupted := copyAppend(s.rawMigrations, "invalid migration script")
+
+	if _, err := m.Apply(stringMigrationsFrom(corrupted...)); err == nil {
 		t.Error("expected an error but got none")
 	}
 
-	if got, want := n, 0; got != want {
-		t.Errorf("applied migrations: got %d, want %d", got, want)
+	history, err := m.History(t.Context())
+	if err != nil {
+		t.Fatalf("m.History() returned an error: %v", err)
 	}
 
-	gotErr, wantPrefix := err.Error(), `schema integrity check failed:`
-	if !strings.HasPrefix(gotErr, wantPrefix) {
-		t.Errorf("unexpected error: got %q, want prefix %q", gotErr, wantPrefix)
+	type entry struct {
+		version int
+		outcome types.Outcome
 	}
 
-	if got, want := currentSchemaVersion(m), len(s.rawMigrations); got != want {
-		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	var want []entry
+	for i := range s.rawMigrations {
+		want = append(want, entry{i + 1, types.OutcomeApplied})
+	}
+
+	// the re-applied migration is rolled back
+	// along with the failed one.
+	want = append(want,
+		entry{len(s.rawMigrations), types.OutcomeReverted},
+		entry{len(s.rawMigrations) + 1, types.OutcomeFailed},
+	)
+
+	got := make([]entry, 0, len(history))
+	for _, h := range history {
+		got = append(got, entry{h.Version, h.Outcome})
+
+		if h.AppliedAt.IsZero() {
+			t.Errorf("history entry %d: missing start time", h.Version)
+		}
+	}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("history mismatch: got %v, want %v", got, want)
+	}
+
+	schema, err := m.CurrentSchemaVersion(t.Context())
+	if err != nil {
+		t.Fatalf("m.CurrentSchemaVersion() returned an error: %v", err)
+	}
+
+	if last := history[len(history)-2]; last.SchemaChecksum != schema.Checksum {
+		t.Errorf("schema checksum mismatch: got %q, want %q", last.SchemaChecksum, schema.Checksum)
 	}
 }
 
-func stringMigrationsFrom(s ...string) migrate.StringMigrations {
-	return migrate.StringMigrations(s)
+func (s *testSuite) applyWithHooks(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+
+	var calls []string
+
+	record := func(name string) migrate.Hook {
+		return func(ctx context.Context, db types.CoreDB, e migrate.HookEvent) error {
+			// the active database handle is usable
+			if _, err := db.ExecContext(ctx, "SELECT 1;"); err != nil {
+				return err
+			}
+
+			calls = append(calls, fmt.Sprintf("%s:%d", name, e.Index))
+
+			return nil
+		}
+	}
+
+	opts := []migrate.Opt{
+		migrate.WithBeforeRun(record("before-run")),
+		migrate.WithBeforeEach(record("before-each")),
+		migrate.WithAfterEach(record("after-each")),
+		migrate.WithAfterRun(record("after-run")),
+		migrate.WithOnError(record("on-error")),
+	}
+	m := migrate.New(db, s.dialect, opts...)
+
+	n, err := m.Apply(stringMigrationsFrom(s.rawMigrations[0]))
+	if err != nil {
+		t.Errorf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, 1; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	want := []string{"before-run:0", "before-each:1", "after-each:1", "after-run:0"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("hook calls mismatch: got %v, want %v", calls, want)
+	}
+
+	// a failing hook aborts the run
+	//
+
+	calls = nil
+	errAbort := errors.New("abort")
+
+	var onErrorEvent migrate.HookEvent
+
+	opts = append(opts,
+		migrate.WithBeforeEach(func(_ context.Context, _ types.CoreDB, e migrate.HookEvent) error {
+			if e.Index == len(s.rawMigrations) {
+				return errAbort
+			}
+
+			return nil
+		}),
+		migrate.WithOnError(func(_ context.Context, _ types.CoreDB, e migrate.HookEvent) error {
+			onErrorEvent = e
+			return nil
+		}),
+	)
+	m = migrate.New(db, s.dialect, opts...)
+
+	n, err = m.Apply(stringMigrationsFrom(s.rawMigrations...))
+	if !errors.Is(err, errAbort) {
+		t.Errorf("unexpected error: got %v, want %v", err, errAbort)
+	}
+
+	if got, want := n, 0; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	if got, want := onErrorEvent.Index, len(s.rawMigrations); got != want {
+		t.Errorf("on error hook index: got %d, want %d", got, want)
+	}
+
+	if onErrorEvent.Err == nil {
+		t.Error("on error hook: expected an error but got none")
+	}
+
+	if got, want := currentSchemaVersion(m), 1; got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+
+	// failures preceding the execution of the migrations are reported
+	//
+
+	onErrorEvent = migrate.HookEvent{}
+
+	corrupted := copyAppend(s.rawMigrations)
+	corrupted[0] += "this string wasn't here before"
+
+	if _, err := m.Apply(stringMigrationsFrom(corrupted...)); !errors.Is(err, migrate.ErrChecksumMismatch) {
+		t.Errorf("unexpected error: got %v, want %v", err, migrate.ErrChecksumMismatch)
+	}
+
+	if !errors.Is(onErrorEvent.Err, migrate.ErrChecksumMismatch) {
+		t.Errorf("on error hook: unexpected error: got %v, want %v", onErrorEvent.Err, migrate.ErrChecksumMismatch)
+	}
+}
+
+func (s *testSuite) applyGoMigrations(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect)
+
+	insert := func(ctx context.Context, db types.CoreDB) error {
+		_, err := db.ExecContext(ctx, "INSERT INTO testing_migration_1 (id, another_id) VALUES (1, 2);")
+		return err
+	}
+
+	migrations := migrate.Scripts{
+		migrate.SQLScript(s.rawMigrations[0]),
+		migrate.GoScript("insert-row", insert),
+		migrate.SQLScript(s.rawMigrations[1]),
+	}
+
+	n, err := m.Apply(migrations)
+	if err != nil {
+		t.Errorf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, len(migrations); got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	var anotherID int
+	if err := db.QueryRowContext(t.Context(), "SELECT another_id FROM testing_migration_1 WHERE id = 1;").Scan(&anotherID); err != nil {
+		t.Errorf("query inserted row: %v", err)
+	}
+
+	if got, want := anotherID, 2; got != want {
+		t.Errorf("inserted row mismatch: got %d, want %d", got, want)
+	}
+
+	// changing the ID of an applied go migration fails validation
+	//
+
+	changed := copyAppend(migrations)
+	changed[1] = migrate.GoScript("insert-another-row", insert)
+
+	if _, err := m.Apply(migrate.Scripts(changed)); err == nil {
+		t.Error("expected an error but got none")
+	}
+
+	// a failing go migration rolls back the run
+	//
+
+	errFailed := errors.New("failed")
+
+	failing := copyAppend(migrations, migrate.GoScript("failing", func(context.Context, types.CoreDB) error {
+		return errFailed
+	}))
+
+	n, err = m.Apply(migrate.Scripts(failing))
+	if !errors.Is(err, errFailed) {
+		t.Errorf("unexpected error: got %v, want %v", err, errFailed)
+	}
+
+	if got, want := n, 0; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	if got, want := currentSchemaVersion(m), len(migrations); got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+}
+
+func (s *testSuite) applyWithLogger(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+
+	var buf bytes.Buffer
+
+	opts := []migrate.Opt{
+		migrate.WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))),
+	}
+	m := migrate.New(db, s.dialect, opts...)
+
+	if _, err := m.Apply(stringMigrationsFrom(s.rawMigrations...)); err != nil {
+		t.Errorf("m.Apply() returned an error: %v", err)
+	}
+
+	var records []string
+
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var r struct {
+			Msg   string `json:"msg"`
+			Index int    `json:"index"`
+		}
+
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("decode log record: %v", err)
+		}
+
+		if r.Index > 0 {
+			r.Msg = fmt.Sprintf("%s:%d", r.Msg, r.Index)
+		}
+
+		records = append(records, r.Msg)
+	}
+
+	want := []string{"schema version read", "schema validated", "migration run started"}
+	for i := range s.rawMigrations {
+		want = append(want, fmt.Sprintf("migration started:%d", i+1), fmt.Sprintf("migration finished:%d", i+1))
+	}
+
+	want = append(want, "transaction committed", "migration run finished")
+
+	if !slices.Equal(records, want) {
+		t.Errorf("log records mismatch: got %v, want %v", records, want)
+	}
+}
+
+func (s *testSuite) applyWithStatementSplitting(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect, migrate.WithStatementSplitting(true))
+
+	script := `
+		-- a comment; with a semicolon
+		CREATE TABLE split_1 (id INTEGER, note TEXT DEFAULT 'a;b');
+		/* another comment; */
+		INSERT INTO split_1 (id) VALUES (1);
+		INSERT INTO split_missing (id) VALUES (1);
+	`
+
+	n, err := m.Apply(stringMigrationsFrom(s.rawMigrations[0], script))
+	if err == nil {
+		t.Error("expected an error but got none")
+	}
+
+	if got, want := n, 0; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	var migrationErr *migrate.MigrationError
+	if !errors.As(err, &migrationErr) {
+		t.Fatalf("unexpected error type: got %T, want %T", err, migrationErr)
+	}
+
+	failed := "INSERT INTO split_missing (id) VALUES (1)"
+
+	if got, want := migrationErr.Statement, failed; got != want {
+		t.Errorf("failed migration statement: got %q, want %q", got, want)
+	}
+
+	if got, want := migrationErr.Offset, strings.Index(script, failed); got != want {
+		t.Errorf("failed statement offset: got %d, want %d", got, want)
+	}
+
+	// drop the failing statement
+	script = script[:migrationErr.Offset]
+
+	n, err = m.Apply(stringMigrationsFrom(s.rawMigrations[0], script))
+	if err != nil {
+		t.Fatalf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, 2; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	var rows int
+	if err := db.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM split_1").Scan(&rows); err != nil {
+		t.Fatalf("query split_1: %v", err)
+	}
+
+	if got, want := rows, 1; got != want {
+		t.Errorf("split_1 rows: got %d, want %d", got, want)
+	}
+}
+
+func (s *testSuite) applyWithMigrationTimeout(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect, migrate.WithMigrationTimeout(time.Nanosecond))
+
+	// the directive overrides the timeout set for the migrator
+	migrations := migrate.Scripts{
+		migrate.SQLScript("-- migrate:timeout 1m\n" + s.rawMigrations[0]),
+		migrate.GoScript("blocking", func(ctx context.Context, _ types.CoreDB) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}),
+	}
+
+	n, err := m.Apply(migrations)
+	if !errors.Is(err, migrate.ErrMigrationTimeout) {
+		t.Fatalf("unexpected error: got %v, want %v", err, migrate.ErrMigrationTimeout)
+	}
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("unexpected error: got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if got, want := n, 0; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	var migrationErr *migrate.MigrationError
+	if !errors.As(err, &migrationErr) {
+		t.Fatalf("unexpected error type: got %T, want %T", err, migrationErr)
+	}
+
+	if got, want := migrationErr.Index, 2; got != want {
+		t.Errorf("failed migration index: got %d, want %d", got, want)
+	}
+
+	// a slow statement is interrupted, and its transaction rolled back
+	//
+
+	slow := migrate.Scripts{
+		migrations[0],
+		migrate.SQLScript("-- migrate:timeout 100ms\n" +
+			"INSERT INTO testing_migration_1 (id) WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 1000000000) SELECT x FROM c;"),
+	}
+
+	_, err = m.Apply(slow)
+	if !errors.Is(err, migrate.ErrMigrationTimeout) || errors.Is(err, migrate.ErrRollbackFailed) {
+		t.Fatalf("unexpected error: got %v, want %v", err, migrate.ErrMigrationTimeout)
+	}
+
+	if got, want := currentSchemaVersion(m), 0; got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+
+	n, err = m.Apply(migrations[:1])
+	if err != nil {
+		t.Fatalf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, 1; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	// an invalid directive fails the run
+	//
+
+	invalid := migrate.StringMigrations{"-- migrate:timeout soon\n" + s.rawMigrations[0]}
+
+	if _, err := migrate.New(db, s.dialect).Apply(invalid); err == nil {
+		t.Error("expected an error but got none")
+	}
+}
+
+func (s *testSuite) applyWithRetry(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+
+	errTransient := errors.New("transient")
+
+	attempts := 0
+	flaky := func(failures int, err error) migrate.Func {
+		return func(context.Context, types.CoreDB) error {
+			attempts++
+			if attempts <= failures {
+				return err
+			}
+
+			return nil
+		}
+	}
+
+	policy := migrate.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		IsTransient: func(err error) bool { return errors.Is(err, errTransient) },
+	}
+
+	var retried []int
+
+	onRetry := func(_ context.Context, _ types.CoreDB, e migrate.HookEvent) error {
+		if e.Err == nil {
+			t.Error("on retry hook: missing error")
+		}
+
+		retried = append(retried, e.Index)
+
+		return nil
+	}
+
+	m := migrate.New(db, s.dialect, migrate.WithRetry(policy), migrate.WithOnRetry(onRetry))
+
+	// the whole transaction is retried
+	migrations := migrate.Scripts{
+		migrate.SQLScript(s.rawMigrations[0]),
+		migrate.GoScript("flaky", flaky(2, errTransient)),
+	}
+
+	n, err := m.Apply(migrations)
+	if err != nil {
+		t.Fatalf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, len(migrations); got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	if got, want := attempts, 3; got != want {
+		t.Errorf("attempts: got %d, want %d", got, want)
+	}
+
+	if want := []int{2, 2}; !slices.Equal(retried, want) {
+		t.Errorf("retried migrations: got %v, want %v", retried, want)
+	}
+
+	// retries are exhausted
+	//
+
+	attempts = 0
+	exhausted := copyAppend(migrations, migrate.GoScript("exhausted", flaky(3, errTransient)))
+
+	if _, err := m.Apply(migrate.Scripts(exhausted)); !errors.Is(err, errTransient) {
+		t.Errorf("unexpected error: got %v, want %v", err, errTransient)
+	}
+
+	if got, want := attempts, policy.MaxAttempts; got != want {
+		t.Errorf("attempts: got %d, want %d", got, want)
+	}
+
+	// non transient errors are not retried
+	//
+
+	attempts = 0
+	errPermanent := errors.New("permanent")
+	permanent := copyAppend(migrations, migrate.GoScript("permanent", flaky(1, errPermanent)))
+
+	if _, err := m.Apply(migrate.Scripts(permanent)); !errors.Is(err, errPermanent) {
+		t.Errorf("unexpected error: got %v, want %v", err, errPermanent)
+	}
+
+	if got, want := attempts, 1; got != want {
+		t.Errorf("attempts: got %d, want %d", got, want)
+	}
+
+	if got, want := currentSchemaVersion(m), len(migrations); got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+}
+
+func (s *testSuite) applyRepeatable(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect)
+
+	view := func(columns string) migrate.Repeatable {
+		return migrate.Repeatable{
+			Name: "testing_view",
+			SQL:  "DROP VIEW IF EXISTS testing_view; CREATE VIEW testing_view AS SELECT " + columns + " FROM testing_migration_1;",
+		}
+	}
+
+	migrations := migrate.RepeatableMigrations{
+		Versioned:  stringMigrationsFrom(s.rawMigrations...),
+		Repeatable: []migrate.Repeatable{view("id")},
+	}
+
+	n, err := m.Apply(migrations)
+	if err != nil {
+		t.Fatalf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, len(s.rawMigrations)+1; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	var rows int
+	if err := db.QueryRowContext(t.Context(), "SELECT COUNT(*) FROM testing_view;").Scan(&rows); err != nil {
+		t.Errorf("query repeatable view: %v", err)
+	}
+
+	// an unchanged repeatable migration is not reapplied
+	//
+
+	n, err = m.Apply(migrations)
+	if err != nil {
+		t.Fatalf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, 0; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	// a changed repeatable migration is reapplied
+	//
+
+	migrations.Repeatable = []migrate.Repeatable{view("id, another_id")}
+
+	plan, err := m.Plan(migrations)
+	if err != nil {
+		t.Fatalf("m.Plan() returned an error: %v", err)
+	}
+
+	if got, want := len(plan.PendingRepeatable), 1; got != want {
+		t.Fatalf("pending repeatable migrations: got %d, want %d", got, want)
+	}
+
+	if got, want := plan.PendingRepeatable[0].Name, "testing_view"; got != want {
+		t.Errorf("pending repeatable migration name: got %q, want %q", got, want)
+	}
+
+	n, err = m.Apply(migrations)
+	if err != nil {
+		t.Fatalf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, 1; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	if err := db.QueryRowContext(t.Context(), "SELECT COUNT(another_id) FROM testing_view;").Scan(&rows); err != nil {
+		t.Errorf("query reapplied repeatable view: %v", err)
+	}
+
+	// a failing repeatable migration is reported by name
+	//
+
+	migrations.Repeatable = append(migrations.Repeatable, migrate.Repeatable{Name: "invalid", SQL: "invalid script"})
+
+	_, err = m.Apply(migrations)
+
+	var migrationErr *migrate.MigrationError
+	if !errors.As(err, &migrationErr) {
+		t.Fatalf("unexpected error type: got %T, want %T", err, migrationErr)
+	}
+
+	if got, want := migrationErr.Name, "invalid"; got != want {
+		t.Errorf("failed migration name: got %q, want %q", got, want)
+	}
+}
+
+func (s *testSuite) applyRepeatableWrapped(t *testing.T) {
+	view := migrate.Repeatable{
+		Name: "testing_view",
+		SQL:  "DROP VIEW IF EXISTS testing_view; CREATE VIEW testing_view AS SELECT id FROM testing_migration_1;",
+	}
+
+	// the go migrations of the wrapped source are kept
+	//
+
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect)
+
+	called := false
+	backfill := func(context.Context, types.CoreDB) error {
+		called = true
+		return nil
+	}
+
+	n, err := m.Apply(migrate.RepeatableMigrations{
+		Versioned:  migrate.Scripts{migrate.SQLScript(s.rawMigrations[0]), migrate.GoScript("backfill-a", backfill)},
+		Repeatable: []migrate.Repeatable{view},
+	})
+	if err != nil {
+		t.Fatalf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, 3; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	if !called {
+		t.Error("go migration was not called")
+	}
+
+	// the names and down scripts of the wrapped source are kept
+	//
+
+	db = s.dbHelper(t.Context(), t)
+	m = migrate.New(db, s.dialect)
+
+	fsys := fstest.MapFS{
+		"migrations/01_migration.up.sql":   {Data: []byte(s.rawMigrations[0])},
+		"migrations/01_migration.down.sql": {Data: []byte(s.rawDownMigrations[0])},
+		"migrations/02_migration.up.sql":   {Data: []byte(s.rawMigrations[1])},
+		"migrations/02_migration.down.sql": {Data: []byte(s.rawDownMigrations[1])},
+	}
+
+	migrations := migrate.RepeatableMigrations{
+		Versioned:  migrate.FSMigrations{FS: fsys, Path: "migrations"},
+		Repeatable: []migrate.Repeatable{view},
+	}
+
+	if _, err := m.Apply(migrations); err != nil {
+		t.Fatalf("m.Apply() returned an error: %v", err)
+	}
+
+	fsys["migrations/03_broken.sql"] = &fstest.MapFile{Data: []byte("invalid script")}
+
+	_, err = m.Apply(migrations)
+
+	var migrationErr *migrate.MigrationError
+	if !errors.As(err, &migrationErr) {
+		t.Fatalf("unexpected error type: got %T, want %T", err, migrationErr)
+	}
+
+	if got, want := migrationErr.Name, "03_broken.sql"; got != want {
+		t.Errorf("failed migration name: got %q, want %q", got, want)
+	}
+
+	delete(fsys, "migrations/03_broken.sql")
+
+	// the view depends on the first migration
+	if _, err := db.ExecContext(t.Context(), "DROP VIEW testing_view;"); err != nil {
+		t.Fatalf("drop view: %v", err)
+	}
+
+	n, err = m.MigrateTo(migrations, 0)
+	if err != nil {
+		t.Fatalf("m.MigrateTo() returned an error: %v", err)
+	}
+
+	if got, want := n, len(s.rawMigrations); got != want {
+		t.Errorf("reverted migrations: got %d, want %d", got, want)
+	}
+
+	if got, want := currentSchemaVersion(m), 0; got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+}
+
+func (s *testSuite) baseline(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect)
+
+	// the first migration was applied by other means
+	if _, err := db.ExecContext(t.Context(), s.rawMigrations[0]); err != nil {
+		t.Fatalf("apply first migration: %v", err)
+	}
+
+	migrations := stringMigrationsFrom(s.rawMigrations...)
+
+	if err := m.Baseline(migrations, len(migrations)+1); err == nil {
+		t.Error("expected an error but got none")
+	}
+
+	if err := m.Baseline(migrations, 1); err != nil {
+		t.Fatalf("m.Baseline() returned an error: %v", err)
+	}
+
+	if got, want := currentSchemaVersion(m), 1; got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+
+	n, err := m.Apply(migrations)
+	if err != nil {
+		t.Fatalf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, len(migrations)-1; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	if err := m.Baseline(migrations, 1); !errors.Is(err, migrate.ErrSchemaVersioned) {
+		t.Errorf("unexpected error: got %v, want %v", err, migrate.ErrSchemaVersioned)
+	}
+
+	if got, want := currentSchemaVersion(m), len(migrations); got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+}
+
+func (s *testSuite) repairAndForceVersion(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect)
+
+	if _, err := m.Repair(stringMigrationsFrom(s.rawMigrations...)); !errors.Is(err, migrate.ErrNoSchemaVersion) {
+		t.Fatalf("unexpected error: got %v, want %v", err, migrate.ErrNoSchemaVersion)
+	}
+
+	if _, err := m.Apply(stringMigrationsFrom(s.rawMigrations...)); err != nil {
+		t.Fatalf("m.Apply() returned an error: %v", err)
+	}
+
+	// an applied migration is edited intentionally
+	edited := copyAppend(s.rawMigrations)
+	edited[0] += "\n-- fixed a typo"
+
+	migrations := stringMigrationsFrom(edited...)
+
+	if _, err := m.Apply(migrations); !errors.Is(err, migrate.ErrChecksumMismatch) {
+		t.Fatalf("unexpected error: got %v, want %v", err, migrate.ErrChecksumMismatch)
+	}
+
+	change, err := m.Repair(migrations)
+	if err != nil {
+		t.Fatalf("m.Repair() returned an error: %v", err)
+	}
+
+	if got, want := change.New.Version, change.Old.Version; got != want {
+		t.Errorf("repaired version mismatch: got %d, want %d", got, want)
+	}
+
+	if change.New.Checksum == change.Old.Checksum {
+		t.Errorf("repaired checksum unchanged: %q", change.New.Checksum)
+	}
+
+	n, err := m.Apply(migrations)
+	if err != nil {
+		t.Fatalf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, 0; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	// forcing a version clears the checksum until repaired
+	//
+
+	change, err = m.ForceVersion(1)
+	if err != nil {
+		t.Fatalf("m.ForceVersion() returned an error: %v", err)
+	}
+
+	if got, want := change.Old.Version, len(migrations); got != want {
+		t.Errorf("old version mismatch: got %d, want %d", got, want)
+	}
+
+	if got, want := currentSchemaVersion(m), 1; got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+
+	if _, err := m.Apply(migrations); !errors.Is(err, migrate.ErrChecksumMismatch) {
+		t.Fatalf("unexpected error: got %v, want %v", err, migrate.ErrChecksumMismatch)
+	}
+
+	if _, err := m.Repair(migrations); err != nil {
+		t.Fatalf("m.Repair() returned an error: %v", err)
+	}
+
+	n, err = m.Apply(migrations)
+	if err != nil {
+		t.Fatalf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, len(migrations)-1; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+}
+
+func (s *testSuite) status(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect, migrate.WithHistory(true))
+
+	if _, err := m.Apply(stringMigrationsFrom(s.rawMigrations[0])); err != nil {
+		t.Fatalf("m.Apply() returned an error: %v", err)
+	}
+
+	states := func(m *migrate.Migrator, migrations migrate.StringMigrations) []string {
+		t.Helper()
+
+		statuses, err := m.Status(migrations)
+		if err != nil {
+			t.Fatalf("m.Status() returned an error: %v", err)
+		}
+
+		got := make([]string, 0, len(statuses))
+
+		for i, st := range statuses {
+			if st.Index != i+1 {
+				t.Errorf("status index mismatch: got %d, want %d", st.Index, i+1)
+			}
+
+			got = append(got, fmt.Sprintf("%s:%s", st.State, st.Drift))
+		}
+
+		return got
+	}
+
+	tests := []struct {
+		name       string
+		m          *migrate.Migrator
+		migrations []string
+		want       []string
+	}{
+		{
+			name:       "unchanged",
+			m:          m,
+			migrations: s.rawMigrations,
+			want:       []string{"applied:none", "pending:"},
+		},
+		{
+			name:       "modified with history",
+			m:          m,
+			migrations: []string{s.rawMigrations[0] + "\n-- modified", s.rawMigrations[1]},
+			want:       []string{"applied:modified", "pending:"},
+		},
+		{
+			name:       "modified without history",
+			m:          migrate.New(db, s.dialect),
+			migrations: []string{s.rawMigrations[0] + "\n-- modified", s.rawMigrations[1]},
+			want:       []string{"applied:unknown", "pending:"},
+		},
+		{
+			name:       "filtered out",
+			m:          migrate.New(db, s.dialect, migrate.WithFilter(func(n int) bool { return n != 2 })),
+			migrations: s.rawMigrations,
+			want:       []string{"applied:none", "filtered-out:"},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := states(tt.m, stringMigrationsFrom(tt.migrations...)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: status mismatch: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func (s *testSuite) reapplyAll(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect)
+
+	n, err := m.Apply(stringMigrationsFrom(s.rawMigrations...))
+	if err != nil {
+		t.Errorf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, len(s.rawMigrations); got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	if got, want := currentSchemaVersion(m), len(s.rawMigrations); got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+
+	opts := []migrate.Opt{
+		migrate.WithReapplyAll(true),
+	}
+	m = migrate.New(db, s.dialect, opts...)
+
+	n, err = m.Apply(stringMigrationsFrom(s.rawMigrations...))
+	if err != nil {
+		t.Errorf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, len(s.rawMigrations); got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	if got, want := currentSchemaVersion(m), len(s.rawMigrations); got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+}
+
+func (s *testSuite) rollsBackOnSQLError(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect)
+
+	if got, want := currentSchemaVersion(m), -1; got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+
+	n, err := m.Apply(stringMigrationsFrom(s.rawMigrations[0]))
+	if err != nil {
+		t.Errorf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, 1; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	if got, want := currentSchemaVersion(m), 1; got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+
+	// run corrupted migration
+	//
+
+	corrupted := copyAppend(s.rawMigrations, "invalid migration script")
+
+	n, err = m.Apply(stringMigrationsFrom(corrupted...))
+	if err == nil {
+		t.Error("expected an error but got none")
+	}
+
+	if got, want := n, 0; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	gotErr, wantPrefix := err.Error(), `apply migration script 3: exec context:`
+	if !strings.HasPrefix(gotErr, wantPrefix) {
+		t.Errorf("unexpected error: got %q, want prefix %q", gotErr, wantPrefix)
+	}
+
+	var migrationErr *migrate.MigrationError
+	if !errors.As(err, &migrationErr) {
+		t.Fatalf("unexpected error type: got %T, want %T", err, migrationErr)
+	}
+
+	if got, want := migrationErr.Index, 3; got != want {
+		t.Errorf("failed migration index: got %d, want %d", got, want)
+	}
+
+	if got, want := migrationErr.Statement, corrupted[2]; got != want {
+		t.Errorf("failed migration statement: got %q, want %q", got, want)
+	}
+
+	if got, want := currentSchemaVersion(m), 1; got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+}
+
+func (s *testSuite) rollsBackOnValidationError(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect)
+
+	if got, want := currentSchemaVersion(m), -1; got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+
+	n, err := m.Apply(stringMigrationsFrom(s.rawMigrations...))
+	if err != nil {
+		t.Errorf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, len(s.rawMigrations); got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	if got, want := currentSchemaVersion(m), len(s.rawMigrations); got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+
+	// run the same migration again
+	//
+
+	n, err = m.Apply(stringMigrationsFrom(s.rawMigrations...))
+	if err != nil {
+		t.Errorf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, 0; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	if got, want := currentSchemaVersion(m), len(s.rawMigrations); got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+
+	corrupted := copyAppend(s.rawMigrations)
+	corrupted[len(corrupted)-1] += "this string wasn't here before"
+
+	// run corrupted migration
+	//
+
+	n, err = m.Apply(stringMigrationsFrom(corrupted...))
+	if err == nil {
+		t.Error("expected an error but got none")
+	}
+
+	if got, want := n, 0; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	gotErr, wantPrefix := err.Error(), `schema integrity check failed:`
+	if !strings.HasPrefix(gotErr, wantPrefix) {
+		t.Errorf("unexpected error: got %q, want prefix %q", gotErr, wantPrefix)
+	}
+
+	if !errors.Is(err, migrate.ErrChecksumMismatch) {
+		t.Errorf("unexpected error: got %v, want %v", err, migrate.ErrChecksumMismatch)
+	}
+
+	if got, want := currentSchemaVersion(m), len(s.rawMigrations); got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+}
+
+func (s *testSuite) rollback(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect)
+
+	migrations := pairedMigrationsFrom(s.rawMigrations, s.rawDownMigrations)
+
+	n, err := m.Apply(migrations)
+	if err != nil {
+		t.Errorf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, len(s.rawMigrations); got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	n, err = m.Rollback(migrations, 1)
+	if err != nil {
+		t.Errorf("m.Rollback() returned an error: %v", err)
+	}
+
+	if got, want := n, len(s.rawMigrations)-1; got != want {
+		t.Errorf("reverted migrations: got %d, want %d", got, want)
+	}
+
+	if got, want := currentSchemaVersion(m), 1; got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+
+	if _, err := db.ExecContext(t.Context(), "SELECT * FROM testing_migration_2;"); err == nil {
+		t.Error("expected reverted table to be dropped")
+	}
+
+	// the reverted migrations can be applied again
+	//
+
+	n, err = m.Apply(migrations)
+	if err != nil {
+		t.Errorf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, len(s.rawMigrations)-1; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	n, err = m.Rollback(migrations, 0)
+	if err != nil {
+		t.Errorf("m.Rollback() returned an error: %v", err)
+	}
+
+	if got, want := n, len(s.rawMigrations); got != want {
+		t.Errorf("reverted migrations: got %d, want %d", got, want)
+	}
+
+	if got, want := currentSchemaVersion(m), 0; got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+
+	// rolling forward is not allowed
+	//
+
+	_, err = m.Rollback(migrations, 1)
+	if err == nil {
+		t.Error("expected an error but got none")
+	}
+}
+
+func (s *testSuite) rollbackIrreversible(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect)
+
+	downs := copyAppend(s.rawDownMigrations)
+	downs[0] = ""
+
+	migrations := pairedMigrationsFrom(s.rawMigrations, downs)
+
+	if _, err := m.Apply(migrations); err != nil {
+		t.Errorf("m.Apply() returned an error: %v", err)
+	}
+
+	n, err := m.Rollback(migrations, 0)
+	if err == nil {
+		t.Error("expected an error but got none")
+	}
+
+	if got, want := n, 0; got != want {
+		t.Errorf("reverted migrations: got %d, want %d", got, want)
+	}
+
+	gotErr, wantPrefix := err.Error(), `revert migration script 1: no down script provided`
+	if !strings.HasPrefix(gotErr, wantPrefix) {
+		t.Errorf("unexpected error: got %q, want prefix %q", gotErr, wantPrefix)
+	}
+
+	if got, want := currentSchemaVersion(m), len(s.rawMigrations); got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+}
+
+func (s *testSuite) rollbackFSMigrations(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect)
+
+	fsys := fstest.MapFS{
+		"migrations/01_migration.up.sql":   {Data: []byte(s.rawMigrations[0])},
+		"migrations/01_migration.down.sql": {Data: []byte(s.rawDownMigrations[0])},
+		"migrations/02_migration.up.sql":   {Data: []byte(s.rawMigrations[1])},
+		"migrations/02_migration.down.sql": {Data: []byte(s.rawDownMigrations[1])},
+	}
+
+	migrations := migrate.FSMigrations{FS: fsys, Path: "migrations"}
+
+	n, err := m.Apply(migrations)
+	if err != nil {
+		t.Errorf("m.Apply() returned an error: %v", err)
+	}
+
+	if got, want := n, len(s.rawMigrations); got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	n, err = m.Rollback(migrations, 0)
+	if err != nil {
+		t.Errorf("m.Rollback() returned an error: %v", err)
+	}
+
+	if got, want := n, len(s.rawMigrations); got != want {
+		t.Errorf("reverted migrations: got %d, want %d", got, want)
+	}
+
+	if got, want := currentSchemaVersion(m), 0; got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+
+	if _, err := db.ExecContext(t.Context(), "SELECT * FROM testing_migration_1;"); err == nil {
+		t.Error("expected reverted table to be dropped")
+	}
+}
+
+func (s *testSuite) migrateTo(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect)
+
+	n, err := m.MigrateTo(stringMigrationsFrom(s.rawMigrations...), 1)
+	if err != nil {
+		t.Errorf("m.MigrateTo() returned an error: %v", err)
+	}
+
+	if got, want := n, 1; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	if got, want := currentSchemaVersion(m), 1; got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+
+	n, err = m.MigrateTo(stringMigrationsFrom(s.rawMigrations...), len(s.rawMigrations))
+	if err != nil {
+		t.Errorf("m.MigrateTo() returned an error: %v", err)
+	}
+
+	if got, want := n, len(s.rawMigrations)-1; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	if got, want := currentSchemaVersion(m), len(s.rawMigrations); got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+
+	// out of range target
+	//
+
+	if _, err := m.MigrateTo(stringMigrationsFrom(s.rawMigrations...), len(s.rawMigrations)+1); err == nil {
+		t.Error("expected an error but got none")
+	}
+
+	// migrating down requires down scripts
+	//
+
+	if _, err := m.MigrateTo(stringMigrationsFrom(s.rawMigrations...), 0); err == nil {
+		t.Error("expected an error but got none")
+	}
+
+	n, err = m.MigrateTo(pairedMigrationsFrom(s.rawMigrations, s.rawDownMigrations), 0)
+	if err != nil {
+		t.Errorf("m.MigrateTo() returned an error: %v", err)
+	}
+
+	if got, want := n, len(s.rawMigrations); got != want {
+		t.Errorf("reverted migrations: got %d, want %d", got, want)
+	}
+
+	if got, want := currentSchemaVersion(m), 0; got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+}
+
+func (s *testSuite) plan(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect)
+
+	plan, err := m.Plan(stringMigrationsFrom(s.rawMigrations...))
+	if err != nil {
+		t.Errorf("m.Plan() returned an error: %v", err)
+	}
+
+	if plan.ValidationErr != nil {
+		t.Errorf("unexpected validation error: %v", plan.ValidationErr)
+	}
+
+	if got, want := plan.CurrentVersion, 0; got != want {
+		t.Errorf("current version: got %d, want %d", got, want)
+	}
+
+	if got, want := plan.TargetVersion, len(s.rawMigrations); got != want {
+		t.Errorf("target version: got %d, want %d", got, want)
+	}
+
+	if got, want := len(plan.Pending), len(s.rawMigrations); got != want {
+		t.Fatalf("pending migrations: got %d, want %d", got, want)
+	}
+
+	for i, p := range plan.Pending {
+		if got, want := p.Index, i+1; got != want {
+			t.Errorf("pending migration index: got %d, want %d", got, want)
+		}
+	}
+
+	// planning does not apply anything
+	//
+
+	if got, want := currentSchemaVersion(m), 0; got != want {
+		t.Errorf("schema version mismatch: got %v, want %v", got, want)
+	}
+
+	if _, err := m.Apply(stringMigrationsFrom(s.rawMigrations[0])); err != nil {
+		t.Errorf("m.Apply() returned an error: %v", err)
+	}
+
+	plan, err = m.Plan(stringMigrationsFrom(s.rawMigrations...))
+	if err != nil {
+		t.Errorf("m.Plan() returned an error: %v", err)
+	}
+
+	if got, want := len(plan.Pending), len(s.rawMigrations)-1; got != want {
+		t.Fatalf("pending migrations: got %d, want %d", got, want)
+	}
+
+	if got, want := plan.Pending[0].Index, 2; got != want {
+		t.Errorf("pending migration index: got %d, want %d", got, want)
+	}
+
+	// validation errors are reported in the plan
+	//
+
+	corrupted := copyAppend(s.rawMigrations)
+	corrupted[0] += "this string wasn't here before"
+
+	plan, err = m.Plan(stringMigrationsFrom(corrupted...))
+	if err != nil {
+		t.Errorf("m.Plan() returned an error: %v", err)
+	}
+
+	if plan.ValidationErr == nil {
+		t.Error("expected a validation error but got none")
+	}
+
+	if got, want := len(plan.Pending), 0; got != want {
+		t.Errorf("pending migrations: got %d, want %d", got, want)
+	}
+
+	// invalid directives of pending migrations are reported in the plan
+	//
+
+	invalid := copyAppend(s.rawMigrations)
+	invalid[1] = "-- migrate:timeout soon\n" + invalid[1]
+
+	plan, err = m.Plan(stringMigrationsFrom(invalid...))
+	if err != nil {
+		t.Errorf("m.Plan() returned an error: %v", err)
+	}
+
+	if plan.ValidationErr == nil {
+		t.Error("expected a validation error but got none")
+	}
+
+	if got, want := len(plan.Pending), 0; got != want {
+		t.Errorf("pending migrations: got %d, want %d", got, want)
+	}
+
+	// as are invalid directives of repeatable migrations
+	//
+
+	plan, err = m.Plan(migrate.RepeatableMigrations{
+		Versioned:  stringMigrationsFrom(s.rawMigrations...),
+		Repeatable: []migrate.Repeatable{{Name: "invalid", SQL: "-- migrate:timeout soon\nSELECT 1;"}},
+	})
+	if err != nil {
+		t.Errorf("m.Plan() returned an error: %v", err)
+	}
+
+	if plan.ValidationErr == nil {
+		t.Error("expected a validation error but got none")
+	}
+
+	if got, want := plan.CurrentVersion, 1; got != want {
+		t.Errorf("current version: got %d, want %d", got, want)
+	}
+
+	if got, want := len(plan.Pending), 0; got != want {
+		t.Errorf("pending migrations: got %d, want %d", got, want)
+	}
+}
+
+func (s *testSuite) applyVersioned(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect)
+
+	first := migrate.VersionedScript{Version: 20240101000000, SQL: s.rawMigrations[0]}
+	third := migrate.VersionedScript{Version: 20240301000000, SQL: s.rawMigrations[1]}
+
+	// a migration merged late from a long-lived branch
+	second := migrate.VersionedScript{Version: 20240201000000, SQL: `CREATE TABLE testing_versioned (id INTEGER PRIMARY KEY);`}
+
+	// listed out of order on purpose
+	n, err := m.ApplyVersioned(migrate.VersionedMigrations{third, first})
+	if err != nil {
+		t.Fatalf("m.ApplyVersioned() returned an error: %v", err)
+	}
+
+	if got, want := n, 2; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	migrations := migrate.VersionedMigrations{first, second, third}
+
+	if _, err := m.ApplyVersioned(migrations); !errors.Is(err, migrate.ErrOutOfOrder) {
+		t.Errorf("unexpected error: got %v, want %v", err, migrate.ErrOutOfOrder)
+	}
+
+	m = migrate.New(db, s.dialect, migrate.WithOutOfOrder(true))
+
+	n, err = m.ApplyVersioned(migrations)
+	if err != nil {
+		t.Fatalf("m.ApplyVersioned() returned an error: %v", err)
+	}
+
+	if got, want := n, 1; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	n, err = m.ApplyVersioned(migrations)
+	if err != nil {
+		t.Fatalf("m.ApplyVersioned() returned an error: %v", err)
+	}
+
+	if got, want := n, 0; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+
+	edited := second
+	edited.SQL += "\n-- edited"
+
+	if _, err := m.ApplyVersioned(migrate.VersionedMigrations{first, edited, third}); !errors.Is(err, migrate.ErrChecksumMismatch) {
+		t.Errorf("unexpected error: got %v, want %v", err, migrate.ErrChecksumMismatch)
+	}
+
+	if _, err := m.ApplyVersioned(migrate.VersionedMigrations{first, third}); !errors.Is(err, migrate.ErrMissingMigration) {
+		t.Errorf("unexpected error: got %v, want %v", err, migrate.ErrMissingMigration)
+	}
+
+	if _, err := m.ApplyVersioned(migrate.VersionedMigrations{first, second, third, third}); err == nil {
+		t.Error("expected an error but got none")
+	}
+
+	broken := migrate.VersionedScript{Version: 20240401000000, SQL: "invalid sql"}
+
+	_, err = m.ApplyVersioned(migrate.VersionedMigrations{first, second, third, broken})
+
+	var me *migrate.MigrationError
+	if !errors.As(err, &me) {
+		t.Fatalf("unexpected error: got %v, want a *migrate.MigrationError", err)
+	}
+
+	if got, want := me.Version, broken.Version; got != want {
+		t.Errorf("failed migration version mismatch: got %d, want %d", got, want)
+	}
+
+	// versions parsed from the file names
+	m = migrate.New(s.dbHelper(t.Context(), t), s.dialect)
+
+	n, err = m.ApplyVersioned(s.embeddedMigrations)
+	if err != nil {
+		t.Fatalf("m.ApplyVersioned() returned an error: %v", err)
+	}
+
+	if got, want := n, 2; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+}
+
+func (s *testSuite) failsOnVersionAhead(t *testing.T) {
+	db := s.dbHelper(t.Context(), t)
+	m := migrate.New(db, s.dialect)
+
+	if _, err := m.Apply(stringMigrationsFrom(s.rawMigrations...)); err != nil {
+		t.Errorf("m.Apply() returned an error: %v", err)
+	}
+
+	n, err := m.Apply(stringMigrationsFrom(s.rawMigrations[0]))
+	if !errors.Is(err, migrate.ErrVersionAhead) {
+		t.Errorf("unexpected error: got %v, want %v", err, migrate.ErrVersionAhead)
+	}
+
+	if got, want := n, 0; got != want {
+		t.Errorf("applied migrations: got %d, want %d", got, want)
+	}
+}
+
+func stringMigrationsFrom(s ...string) migrate.StringMigrations {
+	return migrate.StringMigrations(s)
+}
+
+func pairedMigrationsFrom(ups []string, downs []string) migrate.PairedMigrations {
+	pairs := make(migrate.PairedMigrations, len(ups))
+	for i := range ups {
+		pairs[i] = migrate.MigrationPair{Up: ups[i], Down: downs[i]}
+	}
+
+	return pairs
 }
 
 func currentSchemaVersion(m *migrate.Migrator) int {
@@ -0,0 +1,418 @@
+// Package migrateotel provides OpenTelemetry instrumentation for [migrate.Migrator].
+//
+// A span is created for every migration run, along with a child span
+// for every migration executed within it. The following metrics are recorded:
+//   - migrate.migrations: the number of migrations applied or reverted.
+//   - migrate.runs.failed: the number of failed migration runs.
+//   - migrate.migration.duration: the duration of each executed migration, in seconds.
+//
+// The span and duration of a migration executed within a transaction are only
+// reported once the transaction is done, as successful if it was committed,
+// or with the "rolled_back" outcome otherwise.
+package migrateotel
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/ladzaretti/migrate"
+	"github.com/ladzaretti/migrate/types"
+)
+
+// ScopeName is the instrumentation scope name used
+// for the created tracer and meter.
+const ScopeName = "github.com/ladzaretti/migrate/migrateotel"
+
+// Attribute keys set on the created spans and recorded metrics.
+const (
+	DialectKey  = attribute.Key("db.system.name")
+	IndexKey    = attribute.Key("migrate.index")
+	VersionKey  = attribute.Key("migrate.version")
+	NameKey     = attribute.Key("migrate.name")
+	ChecksumKey = attribute.Key("migrate.checksum")
+	RevertKey   = attribute.Key("migrate.revert")
+	OutcomeKey  = attribute.Key("migrate.outcome")
+)
+
+// Migrator is an instrumented [migrate.Migrator].
+//
+// The Apply, ApplyVersioned, Rollback and MigrateTo methods are traced and measured.
+// All other methods are promoted from the wrapped [migrate.Migrator] as is.
+type Migrator struct {
+	*migrate.Migrator
+
+	tracer  trace.Tracer
+	dialect attribute.KeyValue
+
+	migrations metric.Int64Counter
+	failures   metric.Int64Counter
+	duration   metric.Float64Histogram
+}
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+	migrateOpts    []migrate.Opt
+}
+
+type Opt func(*config)
+
+// WithTracerProvider sets the [trace.TracerProvider] used to create spans.
+// The global provider is used by default.
+func WithTracerProvider(tp trace.TracerProvider) Opt {
+	return func(c *config) {
+		if tp != nil {
+			c.tracerProvider = tp
+		}
+	}
+}
+
+// WithMeterProvider sets the [metric.MeterProvider] used to record metrics.
+// The global provider is used by default.
+func WithMeterProvider(mp metric.MeterProvider) Opt {
+	return func(c *config) {
+		if mp != nil {
+			c.meterProvider = mp
+		}
+	}
+}
+
+// WithMigrateOpts sets the options passed to the wrapped [migrate.Migrator].
+func WithMigrateOpts(opts ...migrate.Opt) Opt {
+	return func(c *config) {
+		c.migrateOpts = append(c.migrateOpts, opts...)
+	}
+}
+
+// New creates a new instrumented [Migrator] with the provided database, dialect, and options.
+func New(db types.DBTX, dialect types.Dialect, opts ...Opt) (*Migrator, error) {
+	c := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	meter := c.meterProvider.Meter(ScopeName)
+
+	migrations, err := meter.Int64Counter("migrate.migrations",
+		metric.WithDescription("Number of migrations applied or reverted."),
+		metric.WithUnit("{migration}"))
+	if err != nil {
+		return nil, fmt.Errorf("create migrations counter: %w", err)
+	}
+
+	failures, err := meter.Int64Counter("migrate.runs.failed",
+		metric.WithDescription("Number of failed migration runs."),
+		metric.WithUnit("{run}"))
+	if err != nil {
+		return nil, fmt.Errorf("create failures counter: %w", err)
+	}
+
+	duration, err := meter.Float64Histogram("migrate.migration.duration",
+		metric.WithDescription("Duration of each executed migration."),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, fmt.Errorf("create duration histogram: %w", err)
+	}
+
+	m := &Migrator{
+		tracer:     c.tracerProvider.Tracer(ScopeName),
+		dialect:    DialectKey.String(dialectName(dialect)),
+		migrations: migrations,
+		failures:   failures,
+		duration:   duration,
+	}
+
+	// the migration span is started before, and ended after,
+	// any of the user provided per migration hooks.
+	migrateOpts := make([]migrate.Opt, 0, len(c.migrateOpts)+4)
+	migrateOpts = append(migrateOpts, migrate.WithBeforeEach(m.beforeEach))
+	migrateOpts = append(migrateOpts, c.migrateOpts...)
+	migrateOpts = append(migrateOpts,
+		migrate.WithAfterEach(m.afterEach),
+		migrate.WithOnError(m.onError),
+		migrate.WithOnRetry(m.onRetry),
+	)
+
+	m.Migrator = migrate.New(db, dialect, migrateOpts...)
+
+	return m, nil
+}
+
+// Apply is a wrapper around [Migrator.ApplyContext] with context.Background.
+func (m *Migrator) Apply(from migrate.Lister) (int, error) {
+	return m.ApplyContext(context.Background(), from)
+}
+
+// ApplyContext traces and measures [migrate.Migrator.ApplyContext].
+func (m *Migrator) ApplyContext(ctx context.Context, from migrate.Lister) (int, error) {
+	return m.trace(ctx, "migrate.apply", func(ctx context.Context) (int, error) {
+		return m.Migrator.ApplyContext(ctx, from)
+	})
+}
+
+// ApplyVersioned is a wrapper around [Migrator.ApplyVersionedContext] with context.Background.
+func (m *Migrator) ApplyVersioned(from migrate.VersionedLister) (int, error) {
+	return m.ApplyVersionedContext(context.Background(), from)
+}
+
+// ApplyVersionedContext traces and measures [migrate.Migrator.ApplyVersionedContext].
+func (m *Migrator) ApplyVersionedContext(ctx context.Context, from migrate.VersionedLister) (int, error) {
+	return m.trace(ctx, "migrate.apply_versioned", func(ctx context.Context) (int, error) {
+		return m.Migrator.ApplyVersionedContext(ctx, from)
+	})
+}
+
+// Rollback is a wrapper around [Migrator.RollbackContext] with context.Background.
+func (m *Migrator) Rollback(from migrate.DownLister, target int) (int, error) {
+	return m.RollbackContext(context.Background(), from, target)
+}
+
+// RollbackContext traces and measures [migrate.Migrator.RollbackContext].
+func (m *Migrator) RollbackContext(ctx context.Context, from migrate.DownLister, target int) (int, error) {
+	return m.trace(ctx, "migrate.rollback", func(ctx context.Context) (int, error) {
+		return m.Migrator.RollbackContext(ctx, from, target)
+	}, attribute.Int("migrate.target", target))
+}
+
+// MigrateTo is a wrapper around [Migrator.MigrateToContext] with context.Background.
+func (m *Migrator) MigrateTo(from migrate.Lister, target int) (int, error) {
+	return m.MigrateToContext(context.Background(), from, target)
+}
+
+// MigrateToContext traces and measures [migrate.Migrator.MigrateToContext].
+func (m *Migrator) MigrateToContext(ctx context.Context, from migrate.Lister, target int) (int, error) {
+	return m.trace(ctx, "migrate.migrate_to", func(ctx context.Context) (int, error) {
+		return m.Migrator.MigrateToContext(ctx, from, target)
+	}, attribute.Int("migrate.target", target))
+}
+
+// trace executes the given migration run within a new span.
+func (m *Migrator) trace(ctx context.Context, name string, fn func(context.Context) (int, error), attrs ...attribute.KeyValue) (int, error) {
+	ctx, span := m.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(append(attrs, m.dialect)...),
+	)
+	defer span.End()
+
+	r := &run{}
+	ctx = context.WithValue(ctx, runKey{}, r)
+
+	n, err := fn(ctx)
+
+	// the last transaction was committed if all the completed migrations were persisted.
+	m.resolve(ctx, r, r.committed+len(r.pending) <= n)
+
+	span.SetAttributes(attribute.Int("migrate.migrations", n))
+
+	// migrations are counted once the run is over, as the migrations
+	// executed within a rolled back transaction were not persisted.
+	if n > 0 {
+		m.migrations.Add(ctx, int64(n), metric.WithAttributes(m.dialect))
+	}
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		m.failures.Add(ctx, 1, metric.WithAttributes(m.dialect))
+
+		return n, err
+	}
+
+	return n, nil
+}
+
+// runKey is the context key of the state of the active migration run.
+type runKey struct{}
+
+// run holds the state of the active migration run.
+type run struct {
+	span  trace.Span
+	start time.Time
+
+	// tx is the transaction the pending migrations were executed in.
+	tx *sql.Tx
+
+	// pending are the completed migrations whose transaction
+	// was not yet committed or rolled back.
+	pending []completed
+
+	// committed is the number of completed migrations known to be committed.
+	committed int
+}
+
+// completed is a migration completed within a transaction,
+// whose span is ended once the transaction is resolved.
+type completed struct {
+	span     trace.Span
+	end      time.Time
+	duration time.Duration
+	revert   bool
+}
+
+func runFromContext(ctx context.Context) *run {
+	r, _ := ctx.Value(runKey{}).(*run)
+	return r
+}
+
+func (m *Migrator) beforeEach(ctx context.Context, db types.CoreDB, e migrate.HookEvent) error {
+	r := runFromContext(ctx)
+	if r == nil {
+		return nil
+	}
+
+	// the pending migrations were committed, as their transaction
+	// is done without being rolled back, see [Migrator.onRetry].
+	if tx, _ := db.(*sql.Tx); tx != r.tx {
+		m.resolve(ctx, r, true)
+		r.tx = tx
+	}
+
+	attrs := []attribute.KeyValue{
+		m.dialect,
+		IndexKey.Int(e.Index),
+		ChecksumKey.String(e.Checksum),
+		RevertKey.Bool(e.Revert),
+	}
+
+	if e.Version != 0 {
+		attrs = append(attrs, VersionKey.Int64(e.Version))
+	}
+
+	if e.Name != "" {
+		attrs = append(attrs, NameKey.String(e.Name))
+	}
+
+	_, r.span = m.tracer.Start(ctx, "migrate.migration",
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attrs...),
+	)
+	r.start = time.Now()
+
+	return nil
+}
+
+// afterEach ends the span of a migration executed outside a transaction.
+// The span of a migration executed within a transaction is ended once
+// the transaction is committed or rolled back, see [Migrator.resolve].
+func (m *Migrator) afterEach(ctx context.Context, db types.CoreDB, e migrate.HookEvent) error {
+	r := runFromContext(ctx)
+	if r == nil || r.span == nil {
+		return nil
+	}
+
+	if _, ok := db.(*sql.Tx); !ok {
+		m.endMigration(ctx, e, "success")
+		r.committed++
+
+		return nil
+	}
+
+	now := time.Now()
+
+	r.pending = append(r.pending, completed{
+		span:     r.span,
+		end:      now,
+		duration: now.Sub(r.start),
+		revert:   e.Revert,
+	})
+	r.span = nil
+
+	return nil
+}
+
+func (m *Migrator) onError(ctx context.Context, _ types.CoreDB, e migrate.HookEvent) error {
+	m.endMigration(ctx, e, "failure")
+	return nil
+}
+
+// onRetry ends the span of the failed attempt of a migration about to be retried,
+// as the retried attempt is traced within a new span. The pending migrations
+// were rolled back along with the failed attempt.
+func (m *Migrator) onRetry(ctx context.Context, _ types.CoreDB, e migrate.HookEvent) error {
+	m.endMigration(ctx, e, "failure")
+
+	if r := runFromContext(ctx); r != nil {
+		m.resolve(ctx, r, false)
+	}
+
+	return nil
+}
+
+// resolve ends the spans of the pending migrations, and records their durations,
+// given whether their transaction was committed or rolled back.
+func (m *Migrator) resolve(ctx context.Context, r *run, committed bool) {
+	outcome := "success"
+	if !committed {
+		outcome = "rolled_back"
+	}
+
+	for _, c := range r.pending {
+		if !committed {
+			c.span.SetStatus(codes.Error, "transaction rolled back")
+		}
+
+		c.span.End(trace.WithTimestamp(c.end))
+
+		m.duration.Record(ctx, c.duration.Seconds(), metric.WithAttributes(
+			m.dialect,
+			RevertKey.Bool(c.revert),
+			OutcomeKey.String(outcome),
+		))
+	}
+
+	if committed {
+		r.committed += len(r.pending)
+	}
+
+	r.pending = nil
+}
+
+// endMigration ends the span of the active migration, if any,
+// and records its duration.
+func (m *Migrator) endMigration(ctx context.Context, e migrate.HookEvent, outcome string) {
+	r := runFromContext(ctx)
+	if r == nil || r.span == nil {
+		return
+	}
+
+	span := r.span
+	r.span = nil
+
+	if e.Err != nil {
+		span.RecordError(e.Err)
+		span.SetStatus(codes.Error, e.Err.Error())
+	}
+
+	span.End()
+
+	m.duration.Record(ctx, time.Since(r.start).Seconds(), metric.WithAttributes(
+		m.dialect,
+		RevertKey.Bool(e.Revert),
+		OutcomeKey.String(outcome),
+	))
+}
+
+// dialectName returns the name reported for the given dialect,
+// following the OpenTelemetry database semantic conventions where possible.
+func dialectName(dialect types.Dialect) string {
+	switch dialect.(type) {
+	case migrate.SQLiteDialect, *migrate.SQLiteDialect:
+		return "sqlite"
+	case migrate.PostgreSQLDialect, *migrate.PostgreSQLDialect:
+		return "postgresql"
+	default:
+		return fmt.Sprintf("%T", dialect)
+	}
+}
@@ -0,0 +1,353 @@
+package migrateotel_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/ladzaretti/migrate"
+	"github.com/ladzaretti/migrate/migrateotel"
+	"github.com/ladzaretti/migrate/types"
+)
+
+var migrations = migrate.StringMigrations{
+	`CREATE TABLE testing_migration_1 (id INTEGER PRIMARY KEY);`,
+	`CREATE TABLE testing_migration_2 (id INTEGER PRIMARY KEY);`,
+}
+
+type instrumented struct {
+	m      *migrateotel.Migrator
+	spans  *tracetest.InMemoryExporter
+	reader *sdkmetric.ManualReader
+}
+
+func newInstrumented(t *testing.T, opts ...migrate.Opt) instrumented {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+
+	t.Cleanup(func() { _ = db.Close() })
+
+	spans := tracetest.NewInMemoryExporter()
+	reader := sdkmetric.NewManualReader()
+
+	m, err := migrateotel.New(db, migrate.SQLiteDialect{},
+		migrateotel.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))),
+		migrateotel.WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+		migrateotel.WithMigrateOpts(opts...),
+	)
+	if err != nil {
+		t.Fatalf("migrateotel.New() returned an error: %v", err)
+	}
+
+	return instrumented{m: m, spans: spans, reader: reader}
+}
+
+func (i instrumented) metrics(t *testing.T) map[string]metricdata.Aggregation {
+	t.Helper()
+
+	var rm metricdata.ResourceMetrics
+	if err := i.reader.Collect(t.Context(), &rm); err != nil {
+		t.Fatalf("collect metrics: %v", err)
+	}
+
+	got := make(map[string]metricdata.Aggregation)
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, md := range sm.Metrics {
+			got[md.Name] = md.Data
+		}
+	}
+
+	return got
+}
+
+func sumOf(t *testing.T, data metricdata.Aggregation) int64 {
+	t.Helper()
+
+	sum, ok := data.(metricdata.Sum[int64])
+	if !ok {
+		t.Fatalf("unexpected aggregation type: %T", data)
+	}
+
+	var total int64
+	for _, dp := range sum.DataPoints {
+		total += dp.Value
+	}
+
+	return total
+}
+
+// histogramCount returns the total count of the data points
+// holding all the given attributes.
+func histogramCount(t *testing.T, data metricdata.Aggregation, attrs ...attribute.KeyValue) uint64 {
+	t.Helper()
+
+	h, ok := data.(metricdata.Histogram[float64])
+	if !ok {
+		t.Fatalf("unexpected aggregation type: %T", data)
+	}
+
+	var total uint64
+
+	for _, dp := range h.DataPoints {
+		if !slices.ContainsFunc(attrs, func(a attribute.KeyValue) bool {
+			v, ok := dp.Attributes.Value(a.Key)
+			return !ok || v != a.Value
+		}) {
+			total += dp.Count
+		}
+	}
+
+	return total
+}
+
+func TestApplyContext(t *testing.T) {
+	i := newInstrumented(t)
+
+	n, err := i.m.ApplyContext(t.Context(), migrations)
+	if err != nil {
+		t.Fatalf("ApplyContext() returned an error: %v", err)
+	}
+
+	if n != len(migrations) {
+		t.Errorf("applied count mismatch: got %d, want %d", n, len(migrations))
+	}
+
+	spans := i.spans.GetSpans()
+	if len(spans) != len(migrations)+1 {
+		t.Fatalf("span count mismatch: got %d, want %d", len(spans), len(migrations)+1)
+	}
+
+	// child spans end before the run span.
+	run := spans[len(spans)-1]
+	if run.Name != "migrate.apply" {
+		t.Errorf("run span name mismatch: got %q, want %q", run.Name, "migrate.apply")
+	}
+
+	for idx, s := range spans[:len(spans)-1] {
+		if s.Name != "migrate.migration" {
+			t.Errorf("migration span name mismatch: got %q, want %q", s.Name, "migrate.migration")
+		}
+
+		if s.Parent.SpanID() != run.SpanContext.SpanID() {
+			t.Errorf("migration span %d is not a child of the run span", idx+1)
+		}
+
+		var index int64
+		for _, a := range s.Attributes {
+			if a.Key == migrateotel.IndexKey {
+				index = a.Value.AsInt64()
+			}
+		}
+
+		if index != int64(idx+1) {
+			t.Errorf("migration span index mismatch: got %d, want %d", index, idx+1)
+		}
+	}
+
+	got := i.metrics(t)
+
+	if n := sumOf(t, got["migrate.migrations"]); n != int64(len(migrations)) {
+		t.Errorf("migrations counter mismatch: got %d, want %d", n, len(migrations))
+	}
+
+	if n := histogramCount(t, got["migrate.migration.duration"]); n != uint64(len(migrations)) {
+		t.Errorf("duration histogram count mismatch: got %d, want %d", n, len(migrations))
+	}
+
+	if _, ok := got["migrate.runs.failed"]; ok {
+		t.Errorf("unexpected failure recorded")
+	}
+}
+
+func TestApplyVersionedContext(t *testing.T) {
+	i := newInstrumented(t)
+
+	versioned := migrate.VersionedMigrations{
+		{Version: 20250101120000, SQL: migrations[0]},
+		{Version: 20250102120000, SQL: migrations[1]},
+	}
+
+	n, err := i.m.ApplyVersionedContext(t.Context(), versioned)
+	if err != nil {
+		t.Fatalf("ApplyVersionedContext() returned an error: %v", err)
+	}
+
+	if n != len(versioned) {
+		t.Errorf("applied count mismatch: got %d, want %d", n, len(versioned))
+	}
+
+	spans := i.spans.GetSpans()
+	if len(spans) != len(versioned)+1 {
+		t.Fatalf("span count mismatch: got %d, want %d", len(spans), len(versioned)+1)
+	}
+
+	run := spans[len(spans)-1]
+	if run.Name != "migrate.apply_versioned" {
+		t.Errorf("run span name mismatch: got %q, want %q", run.Name, "migrate.apply_versioned")
+	}
+
+	for idx, s := range spans[:len(spans)-1] {
+		if s.Parent.SpanID() != run.SpanContext.SpanID() {
+			t.Errorf("migration span %d is not a child of the run span", idx+1)
+		}
+
+		var version int64
+		for _, a := range s.Attributes {
+			if a.Key == migrateotel.VersionKey {
+				version = a.Value.AsInt64()
+			}
+		}
+
+		if version != versioned[idx].Version {
+			t.Errorf("migration span version mismatch: got %d, want %d", version, versioned[idx].Version)
+		}
+	}
+
+	if n := sumOf(t, i.metrics(t)["migrate.migrations"]); n != int64(len(versioned)) {
+		t.Errorf("migrations counter mismatch: got %d, want %d", n, len(versioned))
+	}
+}
+
+func TestApplyContextFailure(t *testing.T) {
+	i := newInstrumented(t)
+
+	broken := append(migrate.StringMigrations{}, migrations...)
+	broken = append(broken, `CREATE TABLE testing_migration_1 (id INTEGER PRIMARY KEY);`)
+
+	if _, err := i.m.ApplyContext(t.Context(), broken); err == nil {
+		t.Fatal("ApplyContext() expected an error")
+	}
+
+	spans := i.spans.GetSpans()
+	if len(spans) != len(broken)+1 {
+		t.Fatalf("span count mismatch: got %d, want %d", len(spans), len(broken)+1)
+	}
+
+	// the migrations preceding the failed one were rolled back.
+	for idx, s := range spans[:len(spans)-1] {
+		if s.Status.Code != codes.Error {
+			t.Errorf("migration span %d status mismatch: got %q, want %q", idx+1, s.Status.Code, "Error")
+		}
+	}
+
+	if n := histogramCount(t, i.metrics(t)["migrate.migration.duration"], migrateotel.OutcomeKey.String("success")); n != 0 {
+		t.Errorf("success duration histogram count mismatch: got %d, want %d", n, 0)
+	}
+
+	if run := spans[len(spans)-1]; run.Status.Code != codes.Error {
+		t.Errorf("run span status mismatch: got %q, want %q", run.Status.Code, "Error")
+	}
+
+	got := i.metrics(t)
+
+	if n := sumOf(t, got["migrate.runs.failed"]); n != 1 {
+		t.Errorf("failures counter mismatch: got %d, want %d", n, 1)
+	}
+
+	// the transaction was rolled back, so nothing was applied.
+	if _, ok := got["migrate.migrations"]; ok {
+		t.Errorf("unexpected migrations recorded")
+	}
+}
+
+func TestApplyContextTxPerMigration(t *testing.T) {
+	i := newInstrumented(t, migrate.WithTransactionPerMigration(true))
+
+	broken := append(migrate.StringMigrations{}, migrations...)
+	broken = append(broken, `CREATE TABLE testing_migration_1 (id INTEGER PRIMARY KEY);`)
+
+	n, err := i.m.ApplyContext(t.Context(), broken)
+	if err == nil {
+		t.Fatal("ApplyContext() expected an error")
+	}
+
+	if n != len(migrations) {
+		t.Errorf("applied count mismatch: got %d, want %d", n, len(migrations))
+	}
+
+	spans := i.spans.GetSpans()
+	if len(spans) != len(broken)+1 {
+		t.Fatalf("span count mismatch: got %d, want %d", len(spans), len(broken)+1)
+	}
+
+	// the migrations committed before the failed one are successful.
+	wantCodes := []codes.Code{codes.Unset, codes.Unset, codes.Error, codes.Error}
+
+	for idx, s := range spans {
+		if s.Status.Code != wantCodes[idx] {
+			t.Errorf("span %q %d status mismatch: got %q, want %q", s.Name, idx+1, s.Status.Code, wantCodes[idx])
+		}
+	}
+
+	if n := histogramCount(t, i.metrics(t)["migrate.migration.duration"], migrateotel.OutcomeKey.String("success")); n != uint64(len(migrations)) {
+		t.Errorf("success duration histogram count mismatch: got %d, want %d", n, len(migrations))
+	}
+}
+
+func TestApplyContextRetry(t *testing.T) {
+	errTransient := errors.New("transient")
+
+	i := newInstrumented(t, migrate.WithRetry(migrate.RetryPolicy{
+		MaxAttempts: 2,
+		Backoff:     time.Millisecond,
+		IsTransient: func(err error) bool { return errors.Is(err, errTransient) },
+	}))
+
+	attempts := 0
+	flaky := migrate.Scripts{
+		migrate.SQLScript(migrations[0]),
+		migrate.GoScript("flaky", func(context.Context, types.CoreDB) error {
+			attempts++
+			if attempts == 1 {
+				return errTransient
+			}
+
+			return nil
+		}),
+	}
+
+	if _, err := i.m.ApplyContext(t.Context(), flaky); err != nil {
+		t.Fatalf("ApplyContext() returned an error: %v", err)
+	}
+
+	// the whole transaction is retried, so every migration is traced twice.
+	spans := i.spans.GetSpans()
+	if want := 2*len(flaky) + 1; len(spans) != want {
+		t.Fatalf("span count mismatch: got %d, want %d", len(spans), want)
+	}
+
+	// the failed attempt is ended first, followed by the rolled back migration preceding it.
+	wantCodes := []codes.Code{codes.Error, codes.Error, codes.Unset, codes.Unset, codes.Unset}
+
+	for idx, s := range spans {
+		if s.Status.Code != wantCodes[idx] {
+			t.Errorf("span %q %d status mismatch: got %q, want %q", s.Name, idx+1, s.Status.Code, wantCodes[idx])
+		}
+	}
+
+	duration := i.metrics(t)["migrate.migration.duration"]
+
+	for outcome, want := range map[string]uint64{"success": 2, "failure": 1, "rolled_back": 1} {
+		if n := histogramCount(t, duration, migrateotel.OutcomeKey.String(outcome)); n != want {
+			t.Errorf("%s duration histogram count mismatch: got %d, want %d", outcome, n, want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/ladzaretti/migrate/internal/schemaops"
 	"github.com/ladzaretti/migrate/types"
@@ -75,3 +76,225 @@ func TestDialect(ctx context.Context, db *sql.DB, dialect types.Dialect) error {
 
 	return nil
 }
+
+// TestHistoryDialect performs an acceptance test on the provided history dialect,
+// verifying its behavior with migration history operations (create, insert, retrieve).
+//
+// The following invariants are tested and must apply for any [types.HistoryDialect]:
+//   - history table is created/exists
+//   - history entries can be saved
+//   - history entries are retrieved in insertion order
+func TestHistoryDialect(ctx context.Context, db *sql.DB, dialect types.HistoryDialect) error {
+	if err := schemaops.CreateHistoryTable(ctx, db, dialect); err != nil {
+		return fmt.Errorf("create history table: %w", err)
+	}
+
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	want := []types.HistoryEntry{
+		{
+			Version:        2,
+			Checksum:       "checksum2",
+			SchemaChecksum: "schema_checksum2",
+			AppliedAt:      start,
+			Duration:       1500 * time.Millisecond,
+			Outcome:        types.OutcomeApplied,
+		},
+		{
+			Version:        1,
+			Checksum:       "checksum1",
+			SchemaChecksum: "schema_checksum1",
+			AppliedAt:      start.Add(time.Minute),
+			Duration:       0,
+			Outcome:        types.OutcomeFailed,
+		},
+	}
+
+	for _, e := range want {
+		if err := schemaops.SaveHistory(ctx, db, dialect, e); err != nil {
+			return fmt.Errorf("save history entry: %w", err)
+		}
+	}
+
+	got, err := schemaops.History(ctx, db, dialect)
+	if err != nil {
+		return fmt.Errorf("fetch history: %w", err)
+	}
+
+	if len(got) != len(want) {
+		return fmt.Errorf("history length mismatch: got %d, want %d", len(got), len(want))
+	}
+
+	for i := range want {
+		if !got[i].AppliedAt.Equal(want[i].AppliedAt) {
+			return fmt.Errorf("history entry %d start time mismatch: got %v, want %v", i, got[i].AppliedAt, want[i].AppliedAt)
+		}
+
+		got[i].AppliedAt = want[i].AppliedAt
+
+		if got[i] != want[i] {
+			return fmt.Errorf("history entry %d mismatch: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	return nil
+}
+
+// TestRepeatableDialect performs an acceptance test on the provided repeatable dialect,
+// verifying its behavior with repeatable migration operations (create, upsert, retrieve).
+//
+// The following invariants are tested and must apply for any [types.RepeatableDialect]:
+//   - repeatable migrations table is created/exists
+//   - checksums can be saved
+//   - checksums are upserted by name
+func TestRepeatableDialect(ctx context.Context, db *sql.DB, dialect types.RepeatableDialect) error {
+	if err := schemaops.CreateRepeatableTable(ctx, db, dialect); err != nil {
+		return fmt.Errorf("create repeatable migrations table: %w", err)
+	}
+
+	saved := [][2]string{
+		{"view", "checksum1"},
+		{"function", "checksum2"},
+		{"view", "checksum3"},
+	}
+
+	for _, s := range saved {
+		if err := schemaops.SaveRepeatable(ctx, db, dialect, s[0], s[1]); err != nil {
+			return fmt.Errorf("save repeatable checksum: %w", err)
+		}
+	}
+
+	got, err := schemaops.RepeatableChecksums(ctx, db, dialect)
+	if err != nil {
+		return fmt.Errorf("fetch repeatable checksums: %w", err)
+	}
+
+	want := map[string]string{"view": "checksum3", "function": "checksum2"}
+
+	if len(got) != len(want) {
+		return fmt.Errorf("repeatable checksums mismatch: got %v, want %v", got, want)
+	}
+
+	for name, checksum := range want {
+		if got[name] != checksum {
+			return fmt.Errorf("repeatable checksums mismatch: got %v, want %v", got, want)
+		}
+	}
+
+	return nil
+}
+
+// TestAppliedVersionsDialect performs an acceptance test on the provided applied versions dialect,
+// verifying its behavior with applied version operations (create, upsert, retrieve).
+//
+// The following invariants are tested and must apply for any [types.AppliedVersionsDialect]:
+//   - applied versions table is created/exists
+//   - 64-bit versions, e.g., timestamps, can be saved
+//   - versions are upserted
+func TestAppliedVersionsDialect(ctx context.Context, db *sql.DB, dialect types.AppliedVersionsDialect) error {
+	if err := schemaops.CreateAppliedVersionsTable(ctx, db, dialect); err != nil {
+		return fmt.Errorf("create applied versions table: %w", err)
+	}
+
+	const (
+		ver1 = int64(20250102030405)
+		ver2 = int64(20250102030406)
+	)
+
+	saved := []struct {
+		version  int64
+		checksum string
+	}{
+		{ver2, "checksum1"},
+		{ver1, "checksum2"},
+		{ver2, "checksum3"},
+	}
+
+	for _, s := range saved {
+		if err := schemaops.SaveAppliedVersion(ctx, db, dialect, s.version, s.checksum); err != nil {
+			return fmt.Errorf("save applied version: %w", err)
+		}
+	}
+
+	got, err := schemaops.AppliedVersions(ctx, db, dialect)
+	if err != nil {
+		return fmt.Errorf("fetch applied versions: %w", err)
+	}
+
+	want := map[int64]string{ver1: "checksum2", ver2: "checksum3"}
+
+	if len(got) != len(want) {
+		return fmt.Errorf("applied versions mismatch: got %v, want %v", got, want)
+	}
+
+	for version, checksum := range want {
+		if got[version] != checksum {
+			return fmt.Errorf("applied versions mismatch: got %v, want %v", got, want)
+		}
+	}
+
+	return nil
+}
+
+// TestLeaseLocker performs an acceptance test on the provided lease locker,
+// verifying its behavior with lease operations (create, acquire, renew, release).
+//
+// The following invariants are tested and must apply for any [types.LeaseLocker]:
+//   - lease table is created/exists
+//   - a free lease can be acquired
+//   - a held lease can neither be acquired nor renewed by another owner
+//   - an expired lease can be taken over
+//   - a released lease can be acquired
+func TestLeaseLocker(ctx context.Context, db *sql.DB, dialect types.LeaseLocker) error {
+	if err := schemaops.CreateLockTable(ctx, db, dialect); err != nil {
+		return fmt.Errorf("create lock table: %w", err)
+	}
+
+	now := time.Now()
+
+	steps := []struct {
+		desc string
+		op   func() (bool, error)
+		want bool
+	}{
+		{"acquire free lease", func() (bool, error) {
+			return schemaops.AcquireLease(ctx, db, dialect, "owner1", now.Add(time.Minute), now)
+		}, true},
+		{"acquire held lease", func() (bool, error) {
+			return schemaops.AcquireLease(ctx, db, dialect, "owner2", now.Add(time.Minute), now)
+		}, false},
+		{"renew lease held by another owner", func() (bool, error) {
+			return schemaops.RenewLease(ctx, db, dialect, "owner2", now.Add(time.Minute))
+		}, false},
+		{"renew held lease", func() (bool, error) {
+			return schemaops.RenewLease(ctx, db, dialect, "owner1", now.Add(time.Second))
+		}, true},
+		{"take over expired lease", func() (bool, error) {
+			later := now.Add(time.Minute)
+			return schemaops.AcquireLease(ctx, db, dialect, "owner2", later.Add(time.Minute), later)
+		}, true},
+		{"renew lease taken over", func() (bool, error) {
+			return schemaops.RenewLease(ctx, db, dialect, "owner1", now.Add(time.Minute))
+		}, false},
+		{"acquire released lease", func() (bool, error) {
+			if err := schemaops.ReleaseLease(ctx, db, dialect, "owner2"); err != nil {
+				return false, err
+			}
+
+			return schemaops.AcquireLease(ctx, db, dialect, "owner1", now.Add(time.Minute), now)
+		}, true},
+	}
+
+	for _, s := range steps {
+		got, err := s.op()
+		if err != nil {
+			return fmt.Errorf("%s: %w", s.desc, err)
+		}
+
+		if got != s.want {
+			return fmt.Errorf("%s: got %v, want %v", s.desc, got, s.want)
+		}
+	}
+
+	return nil
+}
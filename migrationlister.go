@@ -1,8 +1,16 @@
 package migrate
 
 import (
+	"cmp"
+	"context"
 	"embed"
-	"path/filepath"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ladzaretti/migrate/types"
 )
 
 // Lister is an interface that defines a method for listing
@@ -11,6 +19,230 @@ type Lister interface {
 	List() ([]string, error)
 }
 
+// DownLister is a [Lister] that also provides down scripts
+// for reverting the listed migrations.
+//
+// ListDown must return one script per migration returned by List,
+// in the same order. That is, the i-th down script reverts
+// the i-th migration. An empty down script marks the migration
+// as irreversible.
+type DownLister interface {
+	Lister
+	ListDown() ([]string, error)
+}
+
+// Func is a migration implemented as a Go function.
+//
+// It is executed with the same transaction handling as SQL scripts,
+// that is, db is the active transaction, if any.
+type Func func(ctx context.Context, db types.CoreDB) error
+
+// FuncLister is a [Lister] whose migrations may be implemented as Go functions.
+//
+// ListFuncs must return one entry per migration returned by List,
+// in the same order. A non-nil entry is executed in place of the listed
+// script, which then serves as the stable ID of the Go migration
+// when computing checksums.
+type FuncLister interface {
+	Lister
+	ListFuncs() ([]Func, error)
+}
+
+// Repeatable is a repeatable migration, re-applied whenever its script changes.
+//
+// Repeatable migrations are suited for objects that are best kept as a single script,
+// e.g., views, functions and triggers. Their scripts should therefore be idempotent,
+// e.g., using CREATE OR REPLACE.
+type Repeatable struct {
+	// Name uniquely identifies the repeatable migration.
+	Name string
+
+	// SQL is the SQL script of the migration.
+	SQL string
+}
+
+// RepeatableLister is a [Lister] that also provides repeatable migrations.
+//
+// The repeatable migrations are applied by [Migrator.Apply] after all
+// the migrations returned by List, in the order they are listed. Only the
+// repeatable migrations whose checksum differs from the checksum recorded
+// for them the last time they were applied are executed.
+type RepeatableLister interface {
+	Lister
+	ListRepeatable() ([]Repeatable, error)
+}
+
+// RepeatableMigrations is a migrations source combining versioned migrations
+// with repeatable migrations.
+//
+// The contents provided by the optional listing interfaces of the versioned source,
+// e.g., Go function migrations, names and down scripts, are kept.
+//
+// Example:
+//
+//	migrations := migrate.RepeatableMigrations{
+//		Versioned: migrate.EmbeddedMigrations{FS: fs, Path: "migrations"},
+//		Repeatable: []migrate.Repeatable{
+//			{Name: "active_users", SQL: "CREATE OR REPLACE VIEW active_users AS SELECT * FROM users WHERE active;"},
+//		},
+//	}
+type RepeatableMigrations struct {
+	// Versioned is the source of the versioned migrations, if any.
+	Versioned Lister
+
+	// Repeatable holds the repeatable migrations.
+	Repeatable []Repeatable
+}
+
+var (
+	_ RepeatableLister = RepeatableMigrations{}
+
+	_ sourceResolver = RepeatableMigrations{}
+)
+
+func (r RepeatableMigrations) List() ([]string, error) {
+	if r.Versioned == nil {
+		return nil, nil
+	}
+
+	return r.Versioned.List() //nolint:wrapcheck // wrapped by the caller
+}
+
+func (r RepeatableMigrations) ListRepeatable() ([]Repeatable, error) {
+	return r.Repeatable, nil
+}
+
+// resolveSource lists the versioned migrations source, including the contents
+// provided by its optional listing interfaces, e.g., the Go function migrations
+// of [Scripts] or the down scripts of [FSMigrations], and attaches the repeatable migrations.
+func (r RepeatableMigrations) resolveSource() (source, error) {
+	var src source
+
+	if r.Versioned != nil {
+		listed, err := listSource(r.Versioned)
+		if err != nil {
+			return source{}, err
+		}
+
+		src = listed
+	}
+
+	if err := validateRepeatable(r.Repeatable); err != nil {
+		return source{}, err
+	}
+
+	src.repeatable = r.Repeatable
+
+	return src, nil
+}
+
+// VersionedScript is a migration identified by a unique version,
+// e.g., a timestamp such as 20250102150405, rather than by its position.
+type VersionedScript struct {
+	// Version is the unique, positive version of the migration.
+	Version int64
+
+	// Name is the human-readable name of the migration, if any, e.g., its file name.
+	Name string
+
+	// SQL is the SQL script of the migration.
+	SQL string
+}
+
+// VersionedLister is an interface that defines a method for listing
+// migrations identified by unique versions, see [Migrator.ApplyVersioned].
+//
+// The migrations may be listed in any order.
+type VersionedLister interface {
+	ListVersioned() ([]VersionedScript, error)
+}
+
+// VersionedMigrations is a slice of migration scripts identified by unique versions.
+type VersionedMigrations []VersionedScript
+
+var _ VersionedLister = VersionedMigrations{}
+
+func (v VersionedMigrations) ListVersioned() ([]VersionedScript, error) {
+	return v, nil
+}
+
+// Migration is a migration script along with the details describing it.
+type Migration struct {
+	// Version is the version of the migration declared by its source,
+	// e.g., parsed from its file name. It is informational only, as
+	// the [Migrator] versions migrations by their position in the source.
+	Version int64
+
+	// Name is the human-readable name of the migration, e.g., its file name.
+	// It is reported in the logs, errors, hook events and status of the migration.
+	Name string
+
+	// Source describes where the migration was loaded from, e.g., its file path.
+	Source string
+
+	// SQL is the SQL script of the migration.
+	SQL string
+
+	// Metadata holds arbitrary details attached by the source, e.g., the author.
+	// It is not interpreted by the [Migrator].
+	Metadata map[string]string
+}
+
+// MigrationLister is an interface that defines a method for listing
+// migrations along with their names and metadata.
+//
+// The [Migrator] accepts a [Lister] that also implements MigrationLister,
+// in which case ListMigrations must return one entry per script returned
+// by List, in the same order. Use [AsLister] to adapt a MigrationLister
+// that does not implement [Lister].
+type MigrationLister interface {
+	ListMigrations() ([]Migration, error)
+}
+
+// AsLister adapts the given [MigrationLister] to a [Lister],
+// keeping the names of the listed migrations.
+func AsLister(ml MigrationLister) Lister {
+	return migrationListerAdapter{ml}
+}
+
+type migrationListerAdapter struct {
+	MigrationLister
+}
+
+func (a migrationListerAdapter) List() ([]string, error) {
+	migrations, err := a.ListMigrations()
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the caller
+	}
+
+	return Migrations(migrations).List()
+}
+
+// Migrations is a slice of migrations along with their names and metadata.
+//
+// Example:
+//
+//	migrations := migrate.Migrations{
+//		{Name: "create_users", SQL: "CREATE TABLE users (id INTEGER PRIMARY KEY, data TEXT);"},
+//		{Name: "create_users_data_index", SQL: "CREATE INDEX users_data ON users (data);"},
+//	}
+type Migrations []Migration
+
+var _ MigrationLister = Migrations{}
+
+func (ms Migrations) List() ([]string, error) {
+	ss := make([]string, len(ms))
+	for i, m := range ms {
+		ss[i] = m.SQL
+	}
+
+	return ss, nil
+}
+
+func (ms Migrations) ListMigrations() ([]Migration, error) {
+	return ms, nil
+}
+
 // StringMigrations is a slice of plain string migration script queries to be applied.
 type StringMigrations []string
 
@@ -18,48 +250,611 @@ func (s StringMigrations) List() ([]string, error) {
 	return s, nil
 }
 
-// EmbeddedMigrations wraps the [embed.FS] and the path to the migration scripts directory.
-type EmbeddedMigrations struct {
-	FS   embed.FS
+// MigrationPair holds the up and down scripts of a single migration.
+type MigrationPair struct {
+	// Up is the script applying the migration.
+	Up string
+
+	// Down is the script reverting the migration.
+	Down string
+}
+
+// PairedMigrations is a slice of plain string migration scripts
+// paired with the down scripts used to revert them.
+type PairedMigrations []MigrationPair
+
+var _ DownLister = PairedMigrations{}
+
+func (p PairedMigrations) List() ([]string, error) {
+	ss := make([]string, len(p))
+	for i, pair := range p {
+		ss[i] = pair.Up
+	}
+
+	return ss, nil
+}
+
+func (p PairedMigrations) ListDown() ([]string, error) {
+	ss := make([]string, len(p))
+	for i, pair := range p {
+		ss[i] = pair.Down
+	}
+
+	return ss, nil
+}
+
+// Script is a single migration of a [Scripts] source,
+// implemented either as an SQL script or as a Go function.
+//
+// Use [SQLScript] and [GoScript] to create scripts.
+type Script struct {
+	// SQL is the SQL script of the migration.
+	// It is ignored if Func is set.
+	SQL string
+
+	// ID is the stable identifier of a Go function migration.
+	//
+	// It is used in place of the script when computing checksums,
+	// so changing it is detected like editing an applied SQL script.
+	ID string
+
+	// Func is the Go function implementing the migration.
+	Func Func
+}
+
+// SQLScript returns a [Script] applying the given SQL script.
+func SQLScript(sql string) Script {
+	return Script{SQL: sql}
+}
+
+// GoScript returns a [Script] applying the given Go function,
+// identified by the given stable ID.
+func GoScript(id string, fn Func) Script {
+	return Script{ID: id, Func: fn}
+}
+
+// Scripts is a migrations source mixing SQL scripts and Go function migrations.
+//
+// Example:
+//
+//	migrations := migrate.Scripts{
+//		migrate.SQLScript("CREATE TABLE users (id INTEGER PRIMARY KEY, data TEXT);"),
+//		migrate.GoScript("reencode-users-data", reencodeUsersData),
+//	}
+type Scripts []Script
+
+var _ FuncLister = Scripts{}
+
+// List returns the SQL scripts of the migrations,
+// and the IDs of the Go function migrations.
+func (s Scripts) List() ([]string, error) {
+	ss := make([]string, len(s))
+
+	for i, script := range s {
+		if script.Func == nil {
+			ss[i] = script.SQL
+			continue
+		}
+
+		if script.ID == "" {
+			return nil, errf("go migration %d: missing ID", i+1)
+		}
+
+		ss[i] = script.ID
+	}
+
+	return ss, nil
+}
+
+func (s Scripts) ListFuncs() ([]Func, error) {
+	fns := make([]Func, len(s))
+	for i, script := range s {
+		fns[i] = script.Func
+	}
+
+	return fns, nil
+}
+
+// FSMigrations wraps an [fs.FS] and the path to the migration scripts directory within it,
+// e.g., an [os.DirFS], a [testing/fstest.MapFS] or a [archive/zip.Reader].
+//
+// The name of each migration file must start with its numeric version,
+// e.g., "001_create_users.sql" or "20250102150405_create_users.sql".
+// The migrations are ordered by version, compared numerically.
+//
+// A migration may be paired with a down script reverting it, using the ".up.sql"
+// and ".down.sql" suffixes with the same version, e.g., "001_create_users.up.sql"
+// and "001_create_users.down.sql". Migrations without a down file are irreversible.
+// A down file without a matching migration fails the listing with [ErrOrphanDown].
+type FSMigrations struct {
+	FS   fs.FS
 	Path string
+
+	// DisallowGaps fails the listing with [ErrVersionGap]
+	// if the versions of the migration files are not consecutive.
+	DisallowGaps bool
 }
 
-// List returns a list of migration script queries from the embedded file system.
+var (
+	_ DownLister      = FSMigrations{}
+	_ MigrationLister = FSMigrations{}
+	_ VersionedLister = FSMigrations{}
+)
+
+// downSuffix is the file name suffix of down migration files.
+const downSuffix = ".down.sql"
+
+// List returns a list of migration script queries from the file system.
 //
 // It reads migration scripts from the directory specified
-// in the [EmbeddedMigrations.Path] field within the embedded file system [EmbeddedMigrations.FS]
+// in the [FSMigrations.Path] field within the file system [FSMigrations.FS]
 // and returns them as a slice of strings.
 //
 // This function does not recursively read subdirectories.
 //
-// Queries are ordered lexicographically rather than naturally.
+// Queries are ordered naturally by the versions parsed from the file names.
 // For example, the files "1.sql", "2.sql", and "03.sql"
-// will be read in the order: "03.sql", "1.sql", "2.sql".
+// will be read in the order: "1.sql", "2.sql", "03.sql".
 //
-// To ensure correct ordering, use zero-padding for numbers, e.g.,
-// "001.sql", "002.sql", "003.sql".
-func (e EmbeddedMigrations) List() ([]string, error) {
-	files, err := e.FS.ReadDir(e.Path)
+// It fails with [ErrInvalidFileName] if a file name does not start with a version,
+// with [ErrDuplicateVersion] if two files share the same version,
+// and with [ErrVersionGap] on gaps, if disallowed.
+func (f FSMigrations) List() ([]string, error) {
+	files, err := f.readFiles()
 	if err != nil {
-		return nil, errf("reading embedded migration directory: %v", err)
+		return nil, err
 	}
 
-	ss := make([]string, 0, len(files))
+	ss := make([]string, len(files))
+	for i, file := range files {
+		ss[i] = file.content
+	}
+
+	return ss, nil
+}
+
+// ListVersioned returns the migration scripts from the file system,
+// identified by the versions parsed from the leading digits of their file names,
+// e.g., the file "20250102150405_create_users.sql" has version 20250102150405.
+//
+// See [FSMigrations.List] for how the scripts are read.
+func (f FSMigrations) ListVersioned() ([]VersionedScript, error) {
+	files, err := f.readFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	scripts := make([]VersionedScript, len(files))
+	for i, file := range files {
+		scripts[i] = VersionedScript{Version: file.version, Name: file.name, SQL: file.content}
+	}
+
+	return scripts, nil
+}
+
+// ListMigrations returns the migrations from the file system, in the same order
+// as [FSMigrations.List], named by their file names.
+func (f FSMigrations) ListMigrations() ([]Migration, error) {
+	files, err := f.readFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, len(files))
+	for i, file := range files {
+		migrations[i] = file.migration(f.Path)
+	}
+
+	return migrations, nil
+}
+
+// ListDown returns the down scripts of the migrations from the file system,
+// in the same order as [FSMigrations.List]. The down script of a migration
+// without a paired down file is empty.
+func (f FSMigrations) ListDown() ([]string, error) {
+	files, err := f.readFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	ss := make([]string, len(files))
+	for i, file := range files {
+		ss[i] = file.down
+	}
 
-	for _, f := range files {
-		if f.IsDir() {
+	return ss, nil
+}
+
+type migrationFile struct {
+	name    string
+	version int64
+	content string
+
+	// down is the content of the paired down file, if any.
+	down string
+}
+
+func (f migrationFile) migration(dir string) Migration {
+	return Migration{
+		Version: f.version,
+		Name:    f.name,
+		Source:  path.Join(dir, f.name),
+		SQL:     f.content,
+	}
+}
+
+// readFiles reads the migration files, ordered by version,
+// with the down files paired with their migrations.
+//
+// The paths are joined using [path.Join], as [fs.FS] paths are always slash-separated.
+func (f FSMigrations) readFiles() ([]migrationFile, error) {
+	entries, err := fs.ReadDir(f.FS, f.Path)
+	if err != nil {
+		return nil, errf("reading migration directory: %w", err)
+	}
+
+	var files, downs []migrationFile
+
+	for _, e := range entries {
+		if e.IsDir() {
 			continue
 		}
 
-		p := filepath.Join(e.Path, f.Name())
+		v, err := parseVersion(e.Name())
+		if err != nil {
+			return nil, err
+		}
 
-		s, err := e.FS.ReadFile(p)
+		s, err := fs.ReadFile(f.FS, path.Join(f.Path, e.Name()))
 		if err != nil {
-			return nil, errf("reading embedded migration file: %v", err)
+			return nil, errf("reading migration file: %w", err)
+		}
+
+		file := migrationFile{name: e.Name(), version: v, content: string(s)}
+
+		if strings.HasSuffix(e.Name(), downSuffix) {
+			downs = append(downs, file)
+			continue
 		}
 
-		ss = append(ss, string(s))
+		files = append(files, file)
 	}
 
-	return ss, nil
+	slices.SortStableFunc(files, func(a, b migrationFile) int { return cmp.Compare(a.version, b.version) })
+
+	for i := 1; i < len(files); i++ {
+		prev, curr := files[i-1], files[i]
+
+		switch {
+		case prev.version == curr.version:
+			return nil, errf("%w: files %q and %q", ErrDuplicateVersion, prev.name, curr.name)
+		case f.DisallowGaps && curr.version != prev.version+1:
+			return nil, errf("%w: between files %q and %q", ErrVersionGap, prev.name, curr.name)
+		}
+	}
+
+	paired := make(map[int64]string, len(downs))
+
+	for _, d := range downs {
+		if prev, ok := paired[d.version]; ok {
+			return nil, errf("%w: files %q and %q", ErrDuplicateVersion, prev, d.name)
+		}
+
+		i, ok := slices.BinarySearchFunc(files, d.version, func(f migrationFile, v int64) int { return cmp.Compare(f.version, v) })
+		if !ok {
+			return nil, errf("%w: %q", ErrOrphanDown, d.name)
+		}
+
+		paired[d.version] = d.name
+		files[i].down = d.content
+	}
+
+	return files, nil
+}
+
+// EmbeddedMigrations wraps the [embed.FS] and the path to the migration scripts directory.
+//
+// It is an [FSMigrations] over the embedded file system.
+type EmbeddedMigrations struct {
+	FS   embed.FS
+	Path string
+
+	// DisallowGaps fails the listing with [ErrVersionGap]
+	// if the versions of the migration files are not consecutive.
+	DisallowGaps bool
+}
+
+var (
+	_ DownLister      = EmbeddedMigrations{}
+	_ MigrationLister = EmbeddedMigrations{}
+	_ VersionedLister = EmbeddedMigrations{}
+)
+
+// List returns a list of migration script queries from the embedded file system,
+// see [FSMigrations.List].
+func (e EmbeddedMigrations) List() ([]string, error) {
+	return e.fs().List()
+}
+
+// ListVersioned returns the migration scripts from the embedded file system,
+// identified by the versions parsed from their file names, see [FSMigrations.ListVersioned].
+func (e EmbeddedMigrations) ListVersioned() ([]VersionedScript, error) {
+	return e.fs().ListVersioned()
+}
+
+// ListMigrations returns the migrations from the embedded file system,
+// named by their file names, see [FSMigrations.ListMigrations].
+func (e EmbeddedMigrations) ListMigrations() ([]Migration, error) {
+	return e.fs().ListMigrations()
+}
+
+// ListDown returns the down scripts of the migrations from the embedded file system,
+// see [FSMigrations.ListDown].
+func (e EmbeddedMigrations) ListDown() ([]string, error) {
+	return e.fs().ListDown()
+}
+
+func (e EmbeddedMigrations) fs() FSMigrations {
+	return FSMigrations{FS: e.FS, Path: e.Path, DisallowGaps: e.DisallowGaps}
+}
+
+// parseVersion parses the version from the leading digits of the given file name.
+func parseVersion(name string) (int64, error) {
+	digits := name[:len(name)-len(strings.TrimLeft(name, "0123456789"))]
+	if digits == "" {
+		return 0, errf("%w: %q: missing version prefix", ErrInvalidFileName, name)
+	}
+
+	v, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, errf("%w: %q: parse version: %w", ErrInvalidFileName, name, err)
+	}
+
+	return v, nil
+}
+
+// MergeOrder is the ordering strategy of the migrations of a [CompositeMigrations].
+type MergeOrder int
+
+const (
+	// Concatenate orders the migrations of each source in turn,
+	// following the order the sources are given in.
+	Concatenate MergeOrder = iota
+
+	// InterleaveByVersion orders the migrations of all sources by their versions.
+	// Every source must implement [MigrationLister], declaring the version of each
+	// of its migrations, e.g., [FSMigrations] or [Migrations] with versions set.
+	InterleaveByVersion
+)
+
+// CompositeMigrations is a migrations source merging several migration sources,
+// e.g., a core migrations set and the migrations of optional plug-ins.
+//
+// The down scripts, Go function migrations, names and repeatable migrations
+// of the sources are kept. A migration of a source that does not implement
+// [DownLister] is irreversible.
+//
+// Merging fails with [ErrDuplicateVersion] if two migrations declare the same version,
+// see [Migration.Version]. Migrations of sources that do not implement
+// [MigrationLister] declare no version, and are not checked.
+//
+// Example:
+//
+//	migrations := migrate.CompositeMigrations{
+//		Sources: []migrate.Lister{
+//			migrate.EmbeddedMigrations{FS: coreFS, Path: "migrations"},
+//			migrate.EmbeddedMigrations{FS: pluginFS, Path: "migrations"},
+//		},
+//		Order: migrate.InterleaveByVersion,
+//	}
+type CompositeMigrations struct {
+	// Sources are the merged migration sources.
+	Sources []Lister
+
+	// Order is the ordering strategy of the merged migrations.
+	Order MergeOrder
+}
+
+var (
+	_ DownLister       = CompositeMigrations{}
+	_ FuncLister       = CompositeMigrations{}
+	_ MigrationLister  = CompositeMigrations{}
+	_ RepeatableLister = CompositeMigrations{}
+	_ VersionedLister  = CompositeMigrations{}
+
+	_ sourceResolver = CompositeMigrations{}
+)
+
+func (c CompositeMigrations) List() ([]string, error) {
+	src, err := c.resolveSource()
+	if err != nil {
+		return nil, err
+	}
+
+	return src.scripts, nil
+}
+
+func (c CompositeMigrations) ListDown() ([]string, error) {
+	src, err := c.resolveSource()
+	if err != nil {
+		return nil, err
+	}
+
+	return src.downs, nil
+}
+
+func (c CompositeMigrations) ListFuncs() ([]Func, error) {
+	src, err := c.resolveSource()
+	if err != nil {
+		return nil, err
+	}
+
+	return src.funcs, nil
+}
+
+func (c CompositeMigrations) ListMigrations() ([]Migration, error) {
+	src, err := c.resolveSource()
+	if err != nil {
+		return nil, err
+	}
+
+	return src.migrations, nil
+}
+
+// ListVersioned returns the merged migrations identified by their versions,
+// see [Migrator.ApplyVersioned]. Every merged migration must declare a version.
+func (c CompositeMigrations) ListVersioned() ([]VersionedScript, error) {
+	entries, _, err := c.merge()
+	if err != nil {
+		return nil, err
+	}
+
+	scripts := make([]VersionedScript, len(entries))
+
+	for i, e := range entries {
+		if e.migration.Version == 0 {
+			return nil, errf("composite source %d: migration %d: missing version", e.source, e.index)
+		}
+
+		scripts[i] = VersionedScript{Version: e.migration.Version, Name: e.migration.Name, SQL: e.migration.SQL}
+	}
+
+	return scripts, nil
+}
+
+// ListRepeatable returns the repeatable migrations of the sources
+// implementing [RepeatableLister], following the order the sources are given in.
+func (c CompositeMigrations) ListRepeatable() ([]Repeatable, error) {
+	src, err := c.resolveSource()
+	if err != nil {
+		return nil, err
+	}
+
+	return src.repeatable, nil
+}
+
+// resolveSource lists each of the sources once, and merges their contents.
+func (c CompositeMigrations) resolveSource() (source, error) {
+	entries, repeatable, err := c.merge()
+	if err != nil {
+		return source{}, err
+	}
+
+	if err := validateRepeatable(repeatable); err != nil {
+		return source{}, err
+	}
+
+	src := source{
+		scripts:    make([]string, len(entries)),
+		downs:      make([]string, len(entries)),
+		funcs:      make([]Func, len(entries)),
+		migrations: make([]Migration, len(entries)),
+		repeatable: repeatable,
+	}
+
+	for i, e := range entries {
+		src.scripts[i] = e.migration.SQL
+		src.downs[i] = e.down
+		src.funcs[i] = e.fn
+		src.migrations[i] = e.migration
+	}
+
+	return src, nil
+}
+
+// compositeEntry is a single migration of a [CompositeMigrations].
+type compositeEntry struct {
+	migration Migration
+	down      string
+	fn        Func
+
+	// source and index are the 1-based positions of the source
+	// and of the migration within it, used for error reporting.
+	source int
+	index  int
+}
+
+// merge lists the sources, and merges their migrations according to the merge order.
+// The repeatable migrations of the sources are returned following the order
+// the sources are given in.
+func (c CompositeMigrations) merge() ([]compositeEntry, []Repeatable, error) {
+	var (
+		entries    []compositeEntry
+		repeatable []Repeatable
+	)
+
+	for i, from := range c.Sources {
+		src, err := listSource(from)
+		if err != nil {
+			return nil, nil, errf("composite source %d: %w", i+1, err)
+		}
+
+		listed := compositeEntries(src)
+
+		for j := range listed {
+			listed[j].source, listed[j].index = i+1, j+1
+
+			if c.Order == InterleaveByVersion && listed[j].migration.Version == 0 {
+				return nil, nil, errf("composite source %d: migration %d: interleaving by version requires a version", i+1, j+1)
+			}
+		}
+
+		entries = append(entries, listed...)
+		repeatable = append(repeatable, src.repeatable...)
+	}
+
+	switch c.Order {
+	case Concatenate:
+	case InterleaveByVersion:
+		slices.SortStableFunc(entries, func(a, b compositeEntry) int {
+			return cmp.Compare(a.migration.Version, b.migration.Version)
+		})
+	default:
+		return nil, nil, errf("unknown merge order %d", c.Order)
+	}
+
+	seen := make(map[int64]compositeEntry, len(entries))
+
+	for _, e := range entries {
+		v := e.migration.Version
+		if v == 0 {
+			continue
+		}
+
+		if prev, ok := seen[v]; ok {
+			return nil, nil, errf("%w: %d: composite source %d migration %d and composite source %d migration %d",
+				ErrDuplicateVersion, v, prev.source, prev.index, e.source, e.index)
+		}
+
+		seen[v] = e
+	}
+
+	return entries, repeatable, nil
+}
+
+// compositeEntries returns the migrations listed from a single source of a [CompositeMigrations].
+func compositeEntries(src source) []compositeEntry {
+	entries := make([]compositeEntry, len(src.scripts))
+
+	for i, script := range src.scripts {
+		e := compositeEntry{migration: Migration{SQL: script}}
+
+		if src.migrations != nil {
+			// the listed script takes precedence, e.g., the ID of a Go function migration
+			e.migration = src.migrations[i]
+			e.migration.SQL = script
+		}
+
+		if src.downs != nil {
+			e.down = src.downs[i]
+		}
+
+		if src.funcs != nil {
+			e.fn = src.funcs[i]
+		}
+
+		entries[i] = e
+	}
+
+	return entries
 }
@@ -0,0 +1,127 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+)
+
+// Plan describes the migrations [Migrator.Apply] would apply
+// given the current state of the database.
+type Plan struct {
+	// CurrentVersion is the schema version currently recorded in the database.
+	CurrentVersion int
+
+	// TargetVersion is the schema version the database would reach,
+	// that is, the number of available migrations.
+	TargetVersion int
+
+	// Pending lists the migrations that would be applied, in execution order.
+	Pending []PlannedMigration
+
+	// PendingRepeatable lists the repeatable migrations that would be applied
+	// after the pending migrations, in execution order.
+	PendingRepeatable []PlannedMigration
+
+	// ValidationErr holds the reason the database state is rejected
+	// by the migrator, e.g., a checksum mismatch, or the reason a pending
+	// migration is invalid, e.g., a malformed directive.
+	// If set, applying the migrations fails and Pending is empty.
+	ValidationErr error
+}
+
+// PlannedMigration describes a single migration that would be applied.
+type PlannedMigration struct {
+	// Index is the 1-based position of the migration in the execution order,
+	// that is, the schema version recorded once it is applied.
+	Index int
+
+	// Name is the name of the migration, if known, e.g., its file name.
+	// Repeatable migrations are identified by name, and their Index is 0.
+	Name string
+
+	// Checksum is the checksum of the migration script.
+	Checksum string
+
+	// SchemaChecksum is the cumulative checksum recorded once the migration is applied.
+	// It is empty for repeatable migrations.
+	SchemaChecksum string
+}
+
+// Plan reports what [Migrator.Apply] would do with the given migrations,
+// without applying any of them.
+//
+// The [Filter], [WithReapplyAll] and [WithChecksumValidation] options
+// are taken into account. Apart from creating the schema version table,
+// and the repeatable migrations table if needed, no writes are performed.
+//
+// The returned error is only set if the plan could not be computed, e.g.,
+// the migrations source could not be listed. A database state that fails
+// validation is reported using [Plan.ValidationErr].
+func (m *Migrator) Plan(from Lister) (Plan, error) {
+	return m.PlanContext(context.Background(), from)
+}
+
+func (m *Migrator) PlanContext(ctx context.Context, from Lister) (Plan, error) {
+	src, err := listSource(from)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	migrations := src.scripts
+
+	schema, err := m.readVersion(ctx)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	plan := Plan{
+		CurrentVersion: schema.Version,
+		TargetVersion:  len(migrations),
+	}
+
+	runtimeChecksum, err := m.validate(ctx, schema, migrations)
+	if err != nil {
+		plan.ValidationErr = err
+		return plan, nil
+	}
+
+	var pending []step
+
+	if m.reapplyAll || schema.Version < len(migrations) {
+		pending, err = m.applySteps(schema.Version, len(migrations), src, runtimeChecksum)
+		if err != nil {
+			plan.ValidationErr = err
+			return plan, nil
+		}
+	}
+
+	repeatable, err := m.repeatableSteps(ctx, src.repeatable)
+	if err != nil {
+		// an invalid repeatable migration is reported the same as an invalid pending migration.
+		var me *MigrationError
+		if errors.As(err, &me) {
+			plan.ValidationErr = err
+			return plan, nil
+		}
+
+		return Plan{}, err
+	}
+
+	for _, s := range repeatable {
+		plan.PendingRepeatable = append(plan.PendingRepeatable, PlannedMigration{
+			Name:     s.name,
+			Checksum: s.checksum,
+		})
+	}
+
+	for _, s := range pending {
+		plan.Pending = append(plan.Pending, PlannedMigration{
+			Index:          s.index,
+			Name:           s.name,
+			Checksum:       s.checksum,
+			SchemaChecksum: s.schema.Checksum,
+		})
+	}
+
+	return plan, nil
+}
@@ -0,0 +1,158 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/ladzaretti/migrate/internal/schemaops"
+	"github.com/ladzaretti/migrate/types"
+)
+
+// VersionChange describes a manual change of the recorded schema version.
+type VersionChange struct {
+	// Old is the schema version recorded before the change.
+	// It is the zero value if no schema version was recorded.
+	Old types.SchemaVersion
+
+	// New is the schema version recorded by the change.
+	New types.SchemaVersion
+}
+
+// Repair recomputes the cumulative checksum of the currently recorded schema version
+// from the given migrations, and records it in place of the stored checksum.
+//
+// It is used after intentionally editing an already applied migration, e.g., fixing a typo,
+// which otherwise fails the checksum validation of every following run. Unlike disabling
+// the validation and reapplying all migrations, no migrations are executed.
+//
+// The recorded version is kept as is, and must not exceed the number of available
+// migrations. If no schema version is recorded, it fails with [ErrNoSchemaVersion].
+//
+// The schema version table is created if needed.
+// The update is performed within a transaction.
+//
+// It returns the schema version recorded before and after the repair.
+func (m *Migrator) Repair(from Lister) (VersionChange, error) {
+	return m.RepairContext(context.Background(), from)
+}
+
+func (m *Migrator) RepairContext(ctx context.Context, from Lister) (VersionChange, error) {
+	migrations, err := from.List()
+	if err != nil {
+		return VersionChange{}, errf("list migrations source: %w", err)
+	}
+
+	if err := schemaops.CreateTable(ctx, m.db, m.dialect); err != nil {
+		return VersionChange{}, errf("create schema version table: %w", err)
+	}
+
+	return m.changeVersion(ctx, "schema version repaired", func(curr *types.SchemaVersion) (types.SchemaVersion, error) {
+		if curr == nil {
+			return types.SchemaVersion{}, errf("repair: %w", ErrNoSchemaVersion)
+		}
+
+		if curr.Version > len(migrations) {
+			return types.SchemaVersion{}, errf("repair: %w: database version (%d), available migrations (%d)", ErrVersionAhead, curr.Version, len(migrations))
+		}
+
+		return types.SchemaVersion{
+			ID:       curr.ID,
+			Version:  curr.Version,
+			Checksum: m.checksumHistory(migrations)[curr.Version],
+		}, nil
+	})
+}
+
+// ForceVersion records the given version as the current schema version
+// without executing any migrations. It is intended for manual recovery,
+// e.g., after fixing a failed non-transactional migration by hand.
+//
+// As no migrations are given, the recorded checksum is cleared. Following runs
+// fail the checksum validation until [Migrator.Repair] records the checksum
+// matching the migrations, or the validation is disabled using [WithChecksumValidation].
+//
+// The schema version table is created if needed.
+// The update is performed within a transaction.
+//
+// It returns the schema version recorded before and after the change.
+func (m *Migrator) ForceVersion(version int) (VersionChange, error) {
+	return m.ForceVersionContext(context.Background(), version)
+}
+
+func (m *Migrator) ForceVersionContext(ctx context.Context, version int) (VersionChange, error) {
+	if version < 0 {
+		return VersionChange{}, errf("invalid version %d: must not be negative", version)
+	}
+
+	if err := schemaops.CreateTable(ctx, m.db, m.dialect); err != nil {
+		return VersionChange{}, errf("create schema version table: %w", err)
+	}
+
+	return m.changeVersion(ctx, "schema version forced", func(*types.SchemaVersion) (types.SchemaVersion, error) {
+		return types.SchemaVersion{Version: version}, nil
+	})
+}
+
+// changeVersion updates the recorded schema version within a transaction,
+// holding the migration lock if enabled.
+//
+// fn computes the new schema version given the current one, or nil if none is recorded.
+func (m *Migrator) changeVersion(ctx context.Context, msg string, fn func(curr *types.SchemaVersion) (types.SchemaVersion, error)) (VersionChange, error) {
+	var change VersionChange
+
+	_, err := m.withLock(ctx, func(lm *Migrator) (int, error) {
+		tx, err := lm.db.BeginTx(ctx, &sql.TxOptions{})
+		if err != nil {
+			return 0, errf("start transaction: %w", err)
+		}
+
+		change, err = lm.saveChangedVersion(ctx, tx, fn)
+		if err != nil {
+			if err2 := tx.Rollback(); err2 != nil {
+				return 0, errf("%w: %w", ErrRollbackFailed, errors.Join(err2, err))
+			}
+
+			return 0, err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return 0, errf("transaction commit: %w", err)
+		}
+
+		return 0, nil
+	})
+	if err != nil {
+		return VersionChange{}, err
+	}
+
+	m.logger.InfoContext(ctx, msg,
+		"old_version", change.Old.Version, "old_checksum", change.Old.Checksum,
+		"new_version", change.New.Version, "new_checksum", change.New.Checksum,
+	)
+
+	return change, nil
+}
+
+func (m *Migrator) saveChangedVersion(ctx context.Context, tx types.CoreDB, fn func(curr *types.SchemaVersion) (types.SchemaVersion, error)) (VersionChange, error) {
+	curr, err := schemaops.CurrentVersion(ctx, tx, m.dialect)
+	if err != nil && !errors.Is(err, schemaops.ErrNoSchemaVersion) {
+		return VersionChange{}, errf("current schema version: %w", err)
+	}
+
+	schema, err := fn(curr)
+	if err != nil {
+		return VersionChange{}, err
+	}
+
+	if err := schemaops.SaveVersion(ctx, tx, m.dialect, schema); err != nil {
+		return VersionChange{}, errf("save schema version: %w", err)
+	}
+
+	change := VersionChange{New: schema}
+	if curr != nil {
+		change.Old = *curr
+	}
+
+	return change, nil
+}
@@ -0,0 +1,76 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/ladzaretti/migrate/internal/schemaops"
+	"github.com/ladzaretti/migrate/types"
+)
+
+func (m *Migrator) repeatableDialect() (types.RepeatableDialect, error) {
+	rd, ok := m.dialect.(types.RepeatableDialect)
+	if !ok {
+		return nil, errf("repeatable migrations are not supported by dialect %T", m.dialect)
+	}
+
+	return rd, nil
+}
+
+// repeatableSteps returns the steps applying the given repeatable migrations
+// whose checksum differs from the one recorded for them, or all of them
+// if [WithReapplyAll] is enabled.
+//
+// The repeatable migrations table is created if needed.
+func (m *Migrator) repeatableSteps(ctx context.Context, repeatable []Repeatable) ([]step, error) {
+	if len(repeatable) == 0 {
+		return nil, nil
+	}
+
+	rd, err := m.repeatableDialect()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := schemaops.CreateRepeatableTable(ctx, m.db, rd); err != nil {
+		return nil, errf("create repeatable migrations table: %w", err)
+	}
+
+	applied, err := schemaops.RepeatableChecksums(ctx, m.db, rd)
+	if err != nil {
+		return nil, errf("read repeatable migrations checksums: %w", err)
+	}
+
+	var steps []step
+
+	for _, r := range repeatable {
+		s, err := m.newStep(0, r.Name, r.SQL, types.SchemaVersion{}, false)
+		if err != nil {
+			return nil, err
+		}
+
+		if !m.reapplyAll && applied[r.Name] == s.checksum {
+			continue
+		}
+
+		s.repeatable = true
+
+		steps = append(steps, s)
+	}
+
+	m.logger.InfoContext(ctx, "repeatable migrations checked", "repeatable", len(repeatable), "changed", len(steps))
+
+	return steps, nil
+}
+
+func (m *Migrator) saveRepeatable(ctx context.Context, db types.CoreDB, s step) error {
+	rd, err := m.repeatableDialect()
+	if err != nil {
+		return err
+	}
+
+	if err := schemaops.SaveRepeatable(ctx, db, rd, s.name, s.checksum); err != nil {
+		return errf("save repeatable migration checksum: %w", err)
+	}
+
+	return nil
+}
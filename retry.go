@@ -0,0 +1,94 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/ladzaretti/migrate/types"
+)
+
+// RetryPolicy controls the retry of migrations failing with a transient error,
+// such as a lock timeout or a serialization failure.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts, including the first one.
+	// A value of 1 or less disables retries.
+	MaxAttempts int
+
+	// Backoff is the delay before the first retry.
+	// It is doubled for every following retry.
+	Backoff time.Duration
+
+	// MaxBackoff caps the delay between retries, if positive.
+	MaxBackoff time.Duration
+
+	// IsTransient reports whether the given error is transient, i.e., whether
+	// the failed migration should be retried. If nil, the dialect is used if
+	// it implements [types.TransientClassifier]; otherwise, nothing is retried.
+	IsTransient func(err error) bool
+}
+
+// WithRetry sets the [RetryPolicy] used to retry migrations failing with a transient error.
+//
+// With transactions enabled, the whole failed transaction is retried once it is rolled back,
+// i.e., all migrations applied within it. Migrations executed outside a transaction are
+// retried individually. As such, a migration executed outside a transaction should
+// be safe to retry after partially failing.
+func WithRetry(p RetryPolicy) Opt {
+	return func(m *Migrator) {
+		m.retryPolicy = p
+	}
+}
+
+// retry executes fn, retrying it according to the retry policy
+// for as long as it fails with a transient error.
+func (m *Migrator) retry(ctx context.Context, fn func() (int, error)) (int, error) {
+	delay := m.retryPolicy.Backoff
+
+	for attempt := 1; ; attempt++ {
+		n, err := fn()
+		if err == nil || attempt >= m.retryPolicy.MaxAttempts || !m.isTransient(err) {
+			return n, err
+		}
+
+		m.logger.WarnContext(ctx, "transient migration failure, retrying", "attempt", attempt, "delay", delay, "error", err)
+
+		if err2 := m.onRetry(ctx, err); err2 != nil {
+			return n, errors.Join(err, err2)
+		}
+
+		t := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return n, err
+		case <-t.C:
+		}
+
+		delay *= 2
+		if maxDelay := m.retryPolicy.MaxBackoff; maxDelay > 0 && delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+}
+
+// isTransient reports whether the given error is transient, see [RetryPolicy.IsTransient].
+//
+// A failed rollback is never considered transient,
+// as the state of the failed transaction is unknown.
+func (m *Migrator) isTransient(err error) bool {
+	if errors.Is(err, ErrRollbackFailed) {
+		return false
+	}
+
+	if m.retryPolicy.IsTransient != nil {
+		return m.retryPolicy.IsTransient(err)
+	}
+
+	if c, ok := m.dialect.(types.TransientClassifier); ok {
+		return c.IsTransient(err)
+	}
+
+	return false
+}
@@ -0,0 +1,156 @@
+package migrate
+
+import (
+	"context"
+
+	"github.com/ladzaretti/migrate/types"
+)
+
+// MigrationState is the state of a migration in the database.
+type MigrationState string
+
+const (
+	// StateApplied is the state of a migration included in the recorded schema version.
+	StateApplied MigrationState = "applied"
+
+	// StatePending is the state of a migration that would be applied by [Migrator.Apply].
+	StatePending MigrationState = "pending"
+
+	// StateFilteredOut is the state of a migration excluded by the [Filter].
+	StateFilteredOut MigrationState = "filtered-out"
+)
+
+// Drift reports whether the script of an applied migration
+// changed since the migration was applied.
+type Drift string
+
+const (
+	// DriftNone is reported for an applied migration whose script is unchanged.
+	DriftNone Drift = "none"
+
+	// DriftModified is reported for an applied migration whose script changed.
+	DriftModified Drift = "modified"
+
+	// DriftUnknown is reported for an applied migration whose drift
+	// cannot be determined, see [Migrator.Status].
+	DriftUnknown Drift = "unknown"
+)
+
+// MigrationStatus describes the state of a single migration.
+type MigrationStatus struct {
+	// Index is the 1-based position of the migration in the execution order.
+	Index int
+
+	// Name is the name of the migration, if known, e.g., its file name.
+	Name string
+
+	// Checksum is the checksum of the migration script.
+	Checksum string
+
+	// State is the state of the migration in the database.
+	State MigrationState
+
+	// Drift reports whether the script changed since it was applied.
+	// It is empty for migrations that are not applied.
+	Drift Drift
+}
+
+// Status reports the state of each of the given migrations in the database.
+//
+// The drift of the applied migrations is determined as follows. If the cumulative
+// checksum recorded for the current schema version matches the migrations, none
+// of them drifted. Otherwise, if history is enabled using [WithHistory], the checksum
+// of each applied migration is compared with the checksum recorded when it was last
+// applied. If neither is conclusive, the drift is reported as [DriftUnknown].
+//
+// Apart from creating the schema version table, and the history table if enabled,
+// no writes are performed.
+func (m *Migrator) Status(from Lister) ([]MigrationStatus, error) {
+	return m.StatusContext(context.Background(), from)
+}
+
+func (m *Migrator) StatusContext(ctx context.Context, from Lister) ([]MigrationStatus, error) {
+	src, err := listSource(from)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := src.scripts
+
+	schema, err := m.readVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	drift, err := m.drift(ctx, schema, migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+
+	for i, script := range migrations {
+		s := MigrationStatus{
+			Index:    i + 1,
+			Name:     src.name(i + 1),
+			Checksum: m.checksum(script),
+		}
+
+		switch {
+		case !m.migrationFilter(s.Index):
+			s.State = StateFilteredOut
+		case s.Index <= schema.Version:
+			s.State = StateApplied
+			s.Drift = drift(s.Index, s.Checksum)
+		default:
+			s.State = StatePending
+		}
+
+		statuses[i] = s
+	}
+
+	return statuses, nil
+}
+
+// drift returns a function reporting the drift of the applied migration
+// of the given index and checksum, see [Migrator.Status].
+func (m *Migrator) drift(ctx context.Context, schema types.SchemaVersion, migrations []string) (func(index int, checksum string) Drift, error) {
+	if schema.Version <= len(migrations) && m.checksumHistory(migrations)[schema.Version] == schema.Checksum {
+		return func(int, string) Drift { return DriftNone }, nil
+	}
+
+	if !m.withHistory {
+		return func(int, string) Drift { return DriftUnknown }, nil
+	}
+
+	entries, err := m.History(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the checksums of the migrations as last applied, unset if last reverted.
+	applied := make(map[int]string)
+
+	for _, e := range entries {
+		switch e.Outcome {
+		case types.OutcomeApplied:
+			applied[e.Version] = e.Checksum
+		case types.OutcomeReverted:
+			delete(applied, e.Version)
+		case types.OutcomeFailed:
+		}
+	}
+
+	return func(index int, checksum string) Drift {
+		recorded, ok := applied[index]
+
+		switch {
+		case !ok:
+			return DriftUnknown
+		case recorded == checksum:
+			return DriftNone
+		default:
+			return DriftModified
+		}
+	}, nil
+}
@@ -0,0 +1,535 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ladzaretti/migrate/internal/schemaops"
+	"github.com/ladzaretti/migrate/internal/sqlsplit"
+	"github.com/ladzaretti/migrate/types"
+)
+
+// step is a single unit of work of a migration run,
+// that is, applying or reverting a single migration script.
+type step struct {
+	// index is the 1-based position of the migration in the execution order.
+	// It is 0 for repeatable migrations.
+	index int
+
+	// name is the name of the migration, if known.
+	// It identifies repeatable migrations.
+	name string
+
+	// script is the script to execute.
+	// For Go function migrations, it holds the migration ID.
+	script string
+
+	// fn is the Go function executed in place of the script, if set.
+	fn Func
+
+	// checksum is the checksum of the script.
+	checksum string
+
+	// schema is the schema version recorded once the script is executed.
+	schema types.SchemaVersion
+
+	// revert is set for steps reverting a migration.
+	revert bool
+
+	// repeatable is set for steps applying a repeatable migration,
+	// recording its checksum instead of a schema version.
+	repeatable bool
+
+	// versioned is set for steps applying a migration of a [VersionedLister],
+	// recording its version as an applied version instead of a schema version.
+	versioned bool
+
+	// version is the version of a migration of a [VersionedLister].
+	version int64
+
+	// recordOnly is set for steps that only record the schema version
+	// without executing a script.
+	recordOnly bool
+
+	// noTx is set for steps that must be executed outside a transaction.
+	noTx bool
+
+	// timeout is the maximum execution time of the step, if positive.
+	timeout time.Duration
+
+	// split is set for steps executing the statements
+	// of the script one at a time, see [WithStatementSplitting].
+	split bool
+
+	// statements holds the statements of a split script.
+	statements []types.Statement
+}
+
+// newStep returns a step executing the given script,
+// configured by the directives declared in it.
+func (m *Migrator) newStep(index int, name string, script string, schema types.SchemaVersion, revert bool) (step, error) {
+	s := step{
+		index:    index,
+		name:     name,
+		script:   script,
+		checksum: m.checksum(script),
+		schema:   schema,
+		revert:   revert,
+		timeout:  m.migrationTimeout,
+	}
+
+	d, err := parseDirectives(script)
+	if err != nil {
+		return step{}, newMigrationError(s, time.Now(), errf("parse directives: %w", err))
+	}
+
+	s.noTx = d.noTx
+
+	if d.timeout > 0 {
+		s.timeout = d.timeout
+	}
+
+	if m.splitStatements {
+		stmts, err := m.split(script)
+		if err != nil {
+			return step{}, newMigrationError(s, time.Now(), errf("split statements: %w", err))
+		}
+
+		s.split, s.statements = true, stmts
+	}
+
+	return s, nil
+}
+
+// historic reports whether the migration of the step is recorded in the history.
+//
+// Repeatable and versioned migrations are tracked by their own tables instead,
+// as they are not identified by a schema version.
+func (s step) historic() bool {
+	return !s.repeatable && !s.versioned
+}
+
+// logArgs returns the structured logging arguments describing the step.
+func (s step) logArgs() []any {
+	if s.repeatable {
+		return []any{"name", s.name, "checksum", s.checksum}
+	}
+
+	args := []any{"index", s.index}
+	if s.versioned {
+		args = append(args, "version", s.version)
+	}
+
+	if s.name != "" {
+		args = append(args, "name", s.name)
+	}
+
+	return append(args, "checksum", s.checksum, "revert", s.revert)
+}
+
+// event returns the hook event describing the step.
+func (s step) event() HookEvent {
+	return HookEvent{
+		Index:    s.index,
+		Version:  s.version,
+		Name:     s.name,
+		Checksum: s.checksum,
+		Revert:   s.revert,
+	}
+}
+
+// applySteps returns the steps applying the pending migrations
+// when moving from the current version up to the target version.
+func (m *Migrator) applySteps(current int, target int, src source, checksums []string) ([]step, error) {
+	if len(src.scripts)+1 != len(checksums) {
+		return nil, errf("mismatched migrations and checksums: expected %d checksums (+1 for initial state), but found %d", len(src.scripts), len(checksums))
+	}
+
+	pending := m.pending(current, target)
+	steps := make([]step, 0, len(pending))
+
+	for _, v := range pending {
+		schema := types.SchemaVersion{Version: v, Checksum: checksums[v]}
+
+		if src.funcs != nil && src.funcs[v-1] != nil {
+			steps = append(steps, m.newFuncStep(v, src.name(v), src.scripts[v-1], src.funcs[v-1], schema))
+			continue
+		}
+
+		s, err := m.newStep(v, src.name(v), src.scripts[v-1], schema, false)
+		if err != nil {
+			return nil, err
+		}
+
+		steps = append(steps, s)
+	}
+
+	return steps, nil
+}
+
+// revertSteps returns the steps reverting the applied migrations
+// when moving from the current version down to the target version.
+//
+// Migrations excluded by the filter are not reverted. The last step
+// records the target version even if the migrations preceding it were
+// filtered out.
+func (m *Migrator) revertSteps(current int, target int, src source, checksums []string) ([]step, error) {
+	var steps []step
+
+	for i := current; i > target; i-- {
+		if !m.migrationFilter(i) {
+			continue
+		}
+
+		if strings.TrimSpace(src.downs[i-1]) == "" {
+			return nil, errf("revert migration script %d: no down script provided", i)
+		}
+
+		s, err := m.newStep(i, src.name(i), src.downs[i-1], types.SchemaVersion{Version: i - 1, Checksum: checksums[i-1]}, true)
+		if err != nil {
+			return nil, err
+		}
+
+		steps = append(steps, s)
+	}
+
+	targetSchema := types.SchemaVersion{Version: target, Checksum: checksums[target]}
+
+	if len(steps) == 0 {
+		return []step{{index: target, schema: targetSchema, revert: true, recordOnly: true}}, nil
+	}
+
+	steps[len(steps)-1].schema = targetSchema
+
+	return steps, nil
+}
+
+// run executes the given steps, surrounded by the run level hooks,
+// and returns the number of migrations applied or reverted.
+func (m *Migrator) run(ctx context.Context, steps []step) (int, error) {
+	start := time.Now()
+
+	m.logger.InfoContext(ctx, "migration run started", "migrations", len(steps))
+
+	if err := runHooks(ctx, m.db, m.hooks.beforeRun, HookEvent{}); err != nil {
+		return 0, errf("before run hook: %w", err)
+	}
+
+	n, err := m.runSteps(ctx, steps)
+	if err != nil {
+		m.logger.ErrorContext(ctx, "migration run failed", "migrations", n, "duration", time.Since(start), "error", err)
+		return n, err
+	}
+
+	if err := runHooks(ctx, m.db, m.hooks.afterRun, HookEvent{}); err != nil {
+		return n, errf("after run hook: %w", err)
+	}
+
+	m.logger.InfoContext(ctx, "migration run finished", "migrations", n, "duration", time.Since(start))
+
+	return n, nil
+}
+
+// runSteps executes the given steps and returns the number
+// of migrations applied or reverted.
+//
+// With transactions enabled, the steps are executed in batches, see [Migrator.batches].
+// Once a batch fails, the following batches are not executed, and only the steps
+// of the previously completed batches are counted.
+//
+// A batch failing with a transient error is retried, see [WithRetry].
+func (m *Migrator) runSteps(ctx context.Context, steps []step) (int, error) {
+	n := 0
+
+	if !m.withTx {
+		for _, s := range steps {
+			k, err := m.retry(ctx, func() (int, error) { return m.execSteps(ctx, m.db, []step{s}) })
+			if err != nil {
+				return n + k, errf("non-transactional migration: %w", m.recordFailure(ctx, err))
+			}
+
+			n += k
+		}
+
+		return n, nil
+	}
+
+	for _, b := range m.batches(steps) {
+		if b.noTx {
+			k, err := m.retry(ctx, func() (int, error) { return m.execSteps(ctx, m.db, b.steps) })
+			if err != nil {
+				return n + k, errf("non-transactional migration: %w", m.recordFailure(ctx, err))
+			}
+
+			n += k
+
+			continue
+		}
+
+		k, err := m.retry(ctx, func() (int, error) { return m.execTx(ctx, b.steps) })
+		if err != nil {
+			return n, m.recordFailure(ctx, err)
+		}
+
+		n += k
+	}
+
+	return n, nil
+}
+
+// newFuncStep returns a step executing the given Go function migration.
+func (m *Migrator) newFuncStep(index int, name string, id string, fn Func, schema types.SchemaVersion) step {
+	return step{
+		index:    index,
+		name:     name,
+		script:   id,
+		fn:       fn,
+		checksum: m.checksum(id),
+		schema:   schema,
+		timeout:  m.migrationTimeout,
+	}
+}
+
+// batch is a group of steps executed together,
+// either within a single transaction or outside of any transaction.
+type batch struct {
+	steps []step
+	noTx  bool
+}
+
+// batches splits the steps into the groups executed together.
+//
+// Steps marked to run outside a transaction form their own batch, splitting
+// the surrounding steps into separate transactions. The remaining steps are
+// grouped into a single transaction, or one transaction per step if
+// [WithTransactionPerMigration] is enabled.
+func (m *Migrator) batches(steps []step) []batch {
+	var (
+		batches []batch
+		curr    []step
+	)
+
+	flush := func() {
+		if len(curr) > 0 {
+			batches = append(batches, batch{steps: curr})
+			curr = nil
+		}
+	}
+
+	for _, s := range steps {
+		if s.noTx {
+			flush()
+
+			batches = append(batches, batch{steps: []step{s}, noTx: true})
+
+			continue
+		}
+
+		curr = append(curr, s)
+
+		if m.txPerMigration {
+			flush()
+		}
+	}
+
+	flush()
+
+	return batches
+}
+
+// execTx executes the given steps within a single transaction.
+// On error, the transaction is rolled back and no steps are counted.
+func (m *Migrator) execTx(ctx context.Context, steps []step) (int, error) {
+	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return 0, errf("start transaction: %w", err)
+	}
+
+	n, err := m.execSteps(ctx, tx, steps)
+	if err != nil {
+		if err2 := tx.Rollback(); err2 != nil && !m.isRolledBack(err2) {
+			m.logger.ErrorContext(ctx, "transaction rollback failed", "error", err2)
+			return 0, errf("%w: %w", ErrRollbackFailed, errors.Join(err2, err))
+		}
+
+		m.logger.WarnContext(ctx, "transaction rolled back", "migrations", len(steps))
+
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		m.logger.ErrorContext(ctx, "transaction commit failed", "error", err)
+		return 0, errf("transaction commit: %w", err)
+	}
+
+	m.logger.InfoContext(ctx, "transaction committed", "migrations", n)
+
+	return n, nil
+}
+
+// isRolledBack reports whether the given rollback error is caused by
+// the transaction being done already, e.g., rolled back by the database
+// as classified by a dialect implementing [types.RollbackClassifier].
+func (m *Migrator) isRolledBack(err error) bool {
+	if errors.Is(err, sql.ErrTxDone) {
+		return true
+	}
+
+	c, ok := m.dialect.(types.RollbackClassifier)
+
+	return ok && c.IsRolledBack(err)
+}
+
+func (m *Migrator) execSteps(ctx context.Context, db types.CoreDB, steps []step) (n int, retErr error) {
+	for _, s := range steps {
+		if err := m.execStep(ctx, db, s); err != nil {
+			retErr = err
+			return
+		}
+
+		if !s.recordOnly {
+			n++
+		}
+	}
+
+	return
+}
+
+func (m *Migrator) execStep(ctx context.Context, db types.CoreDB, s step) error {
+	if s.recordOnly {
+		if err := schemaops.SaveVersion(ctx, db, m.dialect, s.schema); err != nil {
+			return newMigrationError(s, time.Now(), err)
+		}
+
+		return nil
+	}
+
+	start := time.Now()
+
+	m.logger.InfoContext(ctx, "migration started", s.logArgs()...)
+
+	if err := m.execMigration(ctx, db, s, start); err != nil {
+		m.logger.ErrorContext(ctx, "migration failed", append(s.logArgs(), "duration", time.Since(start), "error", err)...)
+		return err
+	}
+
+	m.logger.InfoContext(ctx, "migration finished", append(s.logArgs(), "duration", time.Since(start))...)
+
+	return nil
+}
+
+// execMigration executes the migration of the given step,
+// along with its hooks, and records the resulting schema version.
+//
+// If the step has a timeout, the migration is executed within a child context
+// that is canceled once the timeout elapses.
+func (m *Migrator) execMigration(ctx context.Context, db types.CoreDB, s step, start time.Time) error {
+	if s.timeout <= 0 {
+		if err := m.migrate(ctx, db, s, start); err != nil {
+			return newMigrationError(s, start, err)
+		}
+
+		return nil
+	}
+
+	timeoutErr := errf("%w after %s", ErrMigrationTimeout, s.timeout)
+
+	ctx, cancel := context.WithTimeoutCause(ctx, s.timeout, timeoutErr)
+	defer cancel()
+
+	if err := m.migrate(ctx, db, s, start); err != nil {
+		if errors.Is(context.Cause(ctx), timeoutErr) {
+			err = errf("%w: %w", timeoutErr, err)
+		}
+
+		return newMigrationError(s, start, err)
+	}
+
+	return nil
+}
+
+func (m *Migrator) migrate(ctx context.Context, db types.CoreDB, s step, start time.Time) error {
+	if err := runHooks(ctx, db, m.hooks.beforeEach, s.event()); err != nil {
+		return errf("before hook: %w", err)
+	}
+
+	if err := s.exec(ctx, db); err != nil {
+		return err
+	}
+
+	if err := m.record(ctx, db, s); err != nil {
+		return err
+	}
+
+	if err := m.renewLease(ctx, db); err != nil {
+		return err
+	}
+
+	if m.withHistory && s.historic() {
+		outcome := types.OutcomeApplied
+		if s.revert {
+			outcome = types.OutcomeReverted
+		}
+
+		if err := m.saveHistory(ctx, db, s, start, time.Since(start), outcome); err != nil {
+			return err
+		}
+	}
+
+	if err := runHooks(ctx, db, m.hooks.afterEach, s.event()); err != nil {
+		return errf("after hook: %w", err)
+	}
+
+	return nil
+}
+
+// record records the migration of the given step as applied.
+func (m *Migrator) record(ctx context.Context, db types.CoreDB, s step) error {
+	switch {
+	case s.repeatable:
+		return m.saveRepeatable(ctx, db, s)
+	case s.versioned:
+		return m.saveAppliedVersion(ctx, db, s)
+	default:
+		return schemaops.SaveVersion(ctx, db, m.dialect, s.schema) //nolint:wrapcheck // error is returned from an internal package
+	}
+}
+
+func (s step) exec(ctx context.Context, db types.CoreDB) error {
+	switch {
+	case s.fn != nil:
+		if err := s.fn(ctx, db); err != nil {
+			return errf("go migration %q: %w", s.script, err)
+		}
+	case s.split:
+		for _, stmt := range s.statements {
+			if err := execContext(ctx, db, stmt.SQL); err != nil {
+				return &statementError{stmt: stmt, err: err}
+			}
+		}
+	default:
+		return execContext(ctx, db, s.script)
+	}
+
+	return nil
+}
+
+// split splits the given script into its statements, see [WithStatementSplitting].
+func (m *Migrator) split(script string) ([]types.Statement, error) {
+	if splitter, ok := m.dialect.(types.StatementSplitter); ok {
+		return splitter.SplitStatements(script) //nolint:wrapcheck // wrapped by the caller
+	}
+
+	return sqlsplit.Split(script, sqlsplit.Options{BackslashEscapes: m.backslashEscapes}) //nolint:wrapcheck // error is returned from an internal package
+}
+
+func execContext(ctx context.Context, db types.CoreDB, query string, args ...any) error {
+	if _, err := db.ExecContext(ctx, query, args...); err != nil {
+		return errf("exec context: %w", err)
+	}
+
+	return nil
+}
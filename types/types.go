@@ -3,6 +3,7 @@ package types
 import (
 	"context"
 	"database/sql"
+	"time"
 )
 
 // CoreDB defines a minimal database interface for executing SQL queries.
@@ -46,6 +47,206 @@ type Dialect interface {
 	SaveVersionQuery() string
 }
 
+// Locker is an optional interface a [Dialect] can implement to provide
+// a lock serializing concurrent migration runs against the same database.
+type Locker interface {
+	// LockQuery returns the SQL query for acquiring the migration lock.
+	//
+	// Executing the query must block until the lock is acquired, and the lock
+	// must be released once the session ends, e.g., a PostgreSQL advisory lock.
+	// The wait is bounded by the context of the query, and a failure is not retried.
+	LockQuery() string
+
+	// UnlockQuery returns the SQL query for releasing the migration lock.
+	//
+	// It is executed on the same connection the lock was acquired on.
+	UnlockQuery() string
+}
+
+// LeaseLocker is an optional interface a [Dialect] can implement to provide
+// the migration lock as a lease, for databases without session level locks, e.g., SQLite.
+//
+// The lease is a single row recording its owner and expiry time. The holder renews
+// the lease while running, so a lease that expired, e.g., since its holder was killed
+// mid-run, can be taken over by another run. Times are provided as Unix milliseconds.
+//
+// An acceptance test [migratetest.TestLeaseLocker] is available for
+// verifying custom-defined LeaseLockers.
+type LeaseLocker interface {
+	// CreateLockTableQuery returns the SQL query for creating the lease table.
+	CreateLockTableQuery() string
+
+	// AcquireLockQuery returns the SQL query for acquiring the lease.
+	//
+	// It must insert the lease row, or take it over if it expired, affecting a single row
+	// if the lease was acquired, and no rows if the lease is held by another owner.
+	// The values are provided as positional parameters in the order (owner, expiry time, current time).
+	AcquireLockQuery() string
+
+	// RenewLockQuery returns the SQL query for extending the lease held by an owner,
+	// affecting no rows if the lease is not held by the owner.
+	//
+	// The values are provided as positional parameters in the order (expiry time, owner).
+	RenewLockQuery() string
+
+	// ReleaseLockQuery returns the SQL query for releasing the lease held by an owner.
+	//
+	// The owner is provided as a positional parameter.
+	ReleaseLockQuery() string
+}
+
+// HistoryDialect is an optional interface a [Dialect] can implement to keep
+// a history of the executed migrations alongside the schema version row.
+//
+// An acceptance test [migratetest.TestHistoryDialect] is available for
+// verifying custom-defined HistoryDialects.
+type HistoryDialect interface {
+	// CreateHistoryTableQuery returns the SQL query for creating the history table.
+	//
+	// The history table must include columns to store the following data:
+	// 	- A column for the migration version number,
+	// 	- A column for the migration script checksum,
+	// 	- A column for the cumulative schema checksum,
+	// 	- A column for the execution start time,
+	// 	- A column for the execution duration in milliseconds,
+	// 	- A column for the execution outcome.
+	CreateHistoryTableQuery() string
+
+	// SaveHistoryQuery returns the SQL query for inserting a history entry.
+	//
+	// The values are provided as positional parameters in the order
+	// (version, checksum, schema checksum, start time, duration, outcome).
+	SaveHistoryQuery() string
+
+	// HistoryQuery returns the SQL query for retrieving all history entries
+	// in the order they were inserted.
+	//
+	// The returned columns should be ordered as in SaveHistoryQuery.
+	HistoryQuery() string
+}
+
+// StatementSplitter is an optional interface a [Dialect] can implement to
+// split migration scripts into individual statements, executed one at a time.
+//
+// It is used for drivers that accept a single statement per execution.
+type StatementSplitter interface {
+	// SplitStatements splits the given script into its statements,
+	// ordered as they appear in the script.
+	SplitStatements(script string) ([]Statement, error)
+}
+
+// TransientClassifier is an optional interface a [Dialect] can implement
+// to classify the errors worth retrying, e.g., lock timeouts and serialization failures.
+type TransientClassifier interface {
+	// IsTransient reports whether the given error is transient,
+	// i.e., whether retrying the failed operation may succeed.
+	IsTransient(err error) bool
+}
+
+// RollbackClassifier is an optional interface a [Dialect] can implement
+// to recognize a failed rollback of a transaction already rolled back by the database,
+// e.g., once a statement within it was interrupted.
+type RollbackClassifier interface {
+	// IsRolledBack reports whether the given rollback error is caused by
+	// the transaction being rolled back already.
+	IsRolledBack(err error) bool
+}
+
+// Statement is a single SQL statement of a migration script.
+type Statement struct {
+	// SQL is the statement text, without the terminating semicolon.
+	SQL string
+
+	// Offset is the byte offset of the statement within the script.
+	Offset int
+}
+
+// RepeatableDialect is an optional interface a [Dialect] can implement to keep
+// track of the repeatable migrations, re-applied whenever their script changes.
+//
+// An acceptance test [migratetest.TestRepeatableDialect] is available for
+// verifying custom-defined RepeatableDialects.
+type RepeatableDialect interface {
+	// CreateRepeatableTableQuery returns the SQL query for creating the repeatable migrations table.
+	//
+	// The repeatable migrations table must include columns to store the following data:
+	// 	- A unique column for the repeatable migration name,
+	// 	- A column for the checksum of the last applied script.
+	CreateRepeatableTableQuery() string
+
+	// RepeatableChecksumsQuery returns the SQL query for retrieving
+	// the checksums of all applied repeatable migrations.
+	//
+	// The returned columns should be ordered as follows: name, followed by the checksum.
+	RepeatableChecksumsQuery() string
+
+	// SaveRepeatableQuery returns the SQL query for upserting the checksum of a repeatable migration.
+	//
+	// The values are provided as positional parameters in the order (name, checksum).
+	SaveRepeatableQuery() string
+}
+
+// AppliedVersionsDialect is an optional interface a [Dialect] can implement to keep
+// track of individually applied migration versions, as an alternative to the single
+// schema version row, e.g., for migrations identified by timestamps.
+//
+// An acceptance test [migratetest.TestAppliedVersionsDialect] is available for
+// verifying custom-defined AppliedVersionsDialects.
+type AppliedVersionsDialect interface {
+	// CreateAppliedVersionsTableQuery returns the SQL query for creating the applied versions table.
+	//
+	// The applied versions table must include columns to store the following data:
+	// 	- A unique column for the migration version number (64-bit),
+	// 	- A column for the migration script checksum.
+	CreateAppliedVersionsTableQuery() string
+
+	// AppliedVersionsQuery returns the SQL query for retrieving all applied versions.
+	//
+	// The returned columns should be ordered as follows: version, followed by the checksum.
+	AppliedVersionsQuery() string
+
+	// SaveAppliedVersionQuery returns the SQL query for upserting an applied version.
+	//
+	// The values are provided as positional parameters in the order (version, checksum).
+	SaveAppliedVersionQuery() string
+}
+
+// Outcome is the result of executing a migration.
+type Outcome string
+
+const (
+	// OutcomeApplied is the outcome of a successfully applied migration.
+	OutcomeApplied Outcome = "applied"
+
+	// OutcomeReverted is the outcome of a successfully reverted migration.
+	OutcomeReverted Outcome = "reverted"
+
+	// OutcomeFailed is the outcome of a migration that failed to apply or revert.
+	OutcomeFailed Outcome = "failed"
+)
+
+// HistoryEntry represents a single execution of a migration.
+type HistoryEntry struct {
+	// Version is the version number of the executed migration.
+	Version int
+
+	// Checksum is the checksum of the executed script.
+	Checksum string
+
+	// SchemaChecksum is the cumulative checksum recorded
+	// in the schema version row once the script was executed.
+	SchemaChecksum string
+
+	// AppliedAt is the time the execution started.
+	AppliedAt time.Time
+
+	// Duration is the execution duration.
+	Duration time.Duration
+
+	// Outcome is the execution outcome.
+	Outcome Outcome
+}
+
 // SchemaVersion represents the schema version information for the database.
 type SchemaVersion struct {
 	// ID is the schema version row ID.
@@ -0,0 +1,169 @@
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"slices"
+
+	"github.com/ladzaretti/migrate/internal/schemaops"
+	"github.com/ladzaretti/migrate/types"
+)
+
+// ApplyVersioned applies the given migrations identified by unique versions,
+// e.g., timestamps, as an alternative to the position based versioning of [Migrator.Apply].
+//
+// Each applied version is recorded individually, along with the checksum of its script,
+// so migrations added concurrently on different branches do not collide. The dialect
+// must implement [types.AppliedVersionsDialect]. Versioned migrations are not recorded
+// in the history enabled using [WithHistory].
+//
+// The unapplied migrations are applied in ascending version order.
+// An unapplied migration older than the latest applied migration fails the run
+// with [ErrOutOfOrder], unless enabled using [WithOutOfOrder].
+//
+// With checksum validation enabled, the run fails with [ErrChecksumMismatch] if the
+// script of an applied migration has changed. The run also fails with [ErrMissingMigration]
+// if an applied version is missing from the migrations source.
+//
+// The [Filter] receives the 1-based position of each migration in ascending version order,
+// and [MigrationError.Version] holds the version of a failed migration. The transaction
+// semantics, hooks and the remaining options are the same as for [Migrator.Apply].
+//
+// It returns the number of migrations applied and any error encountered.
+func (m *Migrator) ApplyVersioned(from VersionedLister) (int, error) {
+	return m.ApplyVersionedContext(context.Background(), from)
+}
+
+func (m *Migrator) ApplyVersionedContext(ctx context.Context, from VersionedLister) (n int, retErr error) {
+	defer func() {
+		retErr = m.onError(ctx, retErr)
+	}()
+
+	migrations, err := listVersioned(from)
+	if err != nil {
+		return 0, err
+	}
+
+	return m.withLock(ctx, func(lm *Migrator) (int, error) {
+		return lm.applyVersioned(ctx, migrations)
+	})
+}
+
+// listVersioned lists the given source, ordered by version.
+func listVersioned(from VersionedLister) ([]VersionedScript, error) {
+	listed, err := from.ListVersioned()
+	if err != nil {
+		return nil, errf("list versioned migrations source: %w", err)
+	}
+
+	migrations := slices.Clone(listed)
+	slices.SortFunc(migrations, func(a, b VersionedScript) int { return cmp.Compare(a.Version, b.Version) })
+
+	for i, mig := range migrations {
+		if mig.Version <= 0 {
+			return nil, errf("versioned migration %d: version must be positive", mig.Version)
+		}
+
+		if i > 0 && migrations[i-1].Version == mig.Version {
+			return nil, errf("%w: %d", ErrDuplicateVersion, mig.Version)
+		}
+	}
+
+	return migrations, nil
+}
+
+func (m *Migrator) applyVersioned(ctx context.Context, migrations []VersionedScript) (int, error) {
+	vd, err := m.appliedVersionsDialect()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := schemaops.CreateAppliedVersionsTable(ctx, m.db, vd); err != nil {
+		return 0, errf("create applied versions table: %w", err)
+	}
+
+	applied, err := schemaops.AppliedVersions(ctx, m.db, vd)
+	if err != nil {
+		return 0, errf("read applied versions: %w", err)
+	}
+
+	m.logger.InfoContext(ctx, "applied versions read", "applied", len(applied))
+
+	steps, err := m.versionedSteps(migrations, applied)
+	if err != nil {
+		m.logger.ErrorContext(ctx, "versioned migrations validation failed", "error", err)
+		return 0, err
+	}
+
+	if len(steps) == 0 {
+		m.logger.InfoContext(ctx, "schema up to date", "applied", len(applied))
+		return 0, nil
+	}
+
+	return m.run(ctx, steps)
+}
+
+// versionedSteps validates the given migrations against the applied versions,
+// and returns the steps applying the pending migrations.
+func (m *Migrator) versionedSteps(migrations []VersionedScript, applied map[int64]string) ([]step, error) {
+	var latest int64
+
+	for v := range applied {
+		latest = max(latest, v)
+
+		if !slices.ContainsFunc(migrations, func(mig VersionedScript) bool { return mig.Version == v }) {
+			return nil, errf("%w: version %d", ErrMissingMigration, v)
+		}
+	}
+
+	var steps []step
+
+	for i, mig := range migrations {
+		s, err := m.newStep(i+1, mig.Name, mig.SQL, types.SchemaVersion{}, false)
+		if err != nil {
+			return nil, errf("versioned migration %d: %w", mig.Version, err)
+		}
+
+		checksum, ok := applied[mig.Version]
+
+		if ok && m.withChecksumValidation && checksum != s.checksum {
+			return nil, errf("schema integrity check failed: migration %d: %w", mig.Version, ErrChecksumMismatch)
+		}
+
+		if (ok && !m.reapplyAll) || !m.migrationFilter(s.index) {
+			continue
+		}
+
+		if !ok && mig.Version < latest && !m.outOfOrder {
+			return nil, errf("%w: migration %d is older than the latest applied migration %d", ErrOutOfOrder, mig.Version, latest)
+		}
+
+		s.version, s.versioned = mig.Version, true
+
+		steps = append(steps, s)
+	}
+
+	return steps, nil
+}
+
+func (m *Migrator) appliedVersionsDialect() (types.AppliedVersionsDialect, error) {
+	vd, ok := m.dialect.(types.AppliedVersionsDialect)
+	if !ok {
+		return nil, errf("versioned migrations are not supported by dialect %T", m.dialect)
+	}
+
+	return vd, nil
+}
+
+func (m *Migrator) saveAppliedVersion(ctx context.Context, db types.CoreDB, s step) error {
+	vd, err := m.appliedVersionsDialect()
+	if err != nil {
+		return err
+	}
+
+	if err := schemaops.SaveAppliedVersion(ctx, db, vd, s.version, s.checksum); err != nil {
+		return errf("save applied version: %w", err)
+	}
+
+	return nil
+}